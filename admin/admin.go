@@ -0,0 +1,255 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"go-opentibia-loginserver/banlist"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/logging"
+	"go-opentibia-loginserver/maintenance"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/utils"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backend is the running login server, as seen by the admin API
+type Backend interface {
+	Connections() []models.Connection
+	LoginStats() map[string]uint64
+	BanList() *banlist.BanList
+	Maintenance() maintenance.Status
+	SetMaintenance(enabled bool, backAt string) error
+	ReloadConfig() error
+	Config() config.Config
+}
+
+type Server struct {
+	backend Backend
+	token   string
+	mux     *http.ServeMux
+}
+
+type connectionResponse struct {
+	Id            uint64 `json:"id"`
+	RemoteAddress string `json:"remote_address"`
+	ConnectedAt   string `json:"connected_at"`
+}
+
+type banRequest struct {
+	Ip       string `json:"ip"`
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+type banResponse struct {
+	Ip        string `json:"ip"`
+	Reason    string `json:"reason"`
+	Author    string `json:"author"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+type maintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	BackAt  string `json:"back_at"`
+}
+
+type maintenanceResponse struct {
+	Active    bool   `json:"active"`
+	Scheduled bool   `json:"scheduled"`
+	BackAt    string `json:"back_at,omitempty"`
+}
+
+// NewServer creates the admin API handler; every request must send the token as a bearer token
+func NewServer(backend Backend, token string) *Server {
+	s := &Server{backend: backend, token: token, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("GET /connections", s.handleConnections)
+	s.mux.HandleFunc("GET /stats", s.handleStats)
+	s.mux.HandleFunc("GET /bans", s.handleListBans)
+	s.mux.HandleFunc("POST /bans", s.handleAddBan)
+	s.mux.HandleFunc("DELETE /bans/{ip}", s.handleRemoveBan)
+	s.mux.HandleFunc("GET /maintenance", s.handleGetMaintenance)
+	s.mux.HandleFunc("PUT /maintenance", s.handleSetMaintenance)
+	s.mux.HandleFunc("POST /reload", s.handleReload)
+	s.mux.HandleFunc("GET /config", s.handleConfig)
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// Start serves the admin API in the background
+func (s *Server) Start(hostname string, port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", hostname, port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for admin API: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("admin server stopped", logging.KeyError, err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) handleConnections(w http.ResponseWriter, r *http.Request) {
+	connections := s.backend.Connections()
+
+	response := make([]connectionResponse, 0, len(connections))
+	for _, connection := range connections {
+		response = append(response, connectionResponse{
+			Id:            connection.Id,
+			RemoteAddress: connection.RemoteAddress,
+			ConnectedAt:   formatTime(connection.ConnectedAt),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.backend.LoginStats())
+}
+
+func (s *Server) handleListBans(w http.ResponseWriter, r *http.Request) {
+	bans := s.backend.BanList().List()
+
+	response := make([]banResponse, 0, len(bans))
+	for _, ban := range bans {
+		response = append(response, banResponse{
+			Ip:        utils.Uint32ToIp(ban.Ip).String(),
+			Reason:    ban.Reason,
+			Author:    ban.Author,
+			ExpiresAt: formatTime(ban.ExpiresAt),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) handleAddBan(w http.ResponseWriter, r *http.Request) {
+	var request banRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return
+	}
+
+	ip, err := utils.IpToUint32(request.Ip)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	duration, err := time.ParseDuration(request.Duration)
+	if err != nil || duration <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid duration: %s", request.Duration))
+		return
+	}
+
+	ban := banlist.Ban{
+		Ip:        ip,
+		Reason:    request.Reason,
+		Author:    "admin API",
+		ExpiresAt: time.Now().Add(duration).Unix(),
+	}
+	s.backend.BanList().Add(ban)
+
+	slog.Info("temporary IP ban added", logging.KeyRemoteIp, request.Ip, "duration", duration, "reason", request.Reason)
+	writeJSON(w, http.StatusCreated, banResponse{Ip: request.Ip, Reason: ban.Reason, Author: ban.Author, ExpiresAt: formatTime(ban.ExpiresAt)})
+}
+
+func (s *Server) handleRemoveBan(w http.ResponseWriter, r *http.Request) {
+	ip, err := utils.IpToUint32(r.PathValue("ip"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !s.backend.BanList().Remove(ip) {
+		writeError(w, http.StatusNotFound, "ip is not banned")
+		return
+	}
+
+	slog.Info("temporary IP ban removed", logging.KeyRemoteIp, r.PathValue("ip"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleGetMaintenance(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, newMaintenanceResponse(s.backend.Maintenance()))
+}
+
+func (s *Server) handleSetMaintenance(w http.ResponseWriter, r *http.Request) {
+	var request maintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return
+	}
+
+	if err := s.backend.SetMaintenance(request.Enabled, request.BackAt); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	slog.Info("maintenance mode changed", "enabled", request.Enabled, "back_at", request.BackAt)
+	writeJSON(w, http.StatusOK, newMaintenanceResponse(s.backend.Maintenance()))
+}
+
+func newMaintenanceResponse(status maintenance.Status) maintenanceResponse {
+	return maintenanceResponse{Active: status.Active, Scheduled: status.Scheduled, BackAt: status.BackAt}
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if err := s.backend.ReloadConfig(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := yaml.Marshal(s.backend.Config().Masked())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
+
+func formatTime(unixTime int64) string {
+	return time.Unix(unixTime, 0).UTC().Format(time.RFC3339)
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
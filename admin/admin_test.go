@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-opentibia-loginserver/banlist"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/maintenance"
+	"go-opentibia-loginserver/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	banList     *banlist.BanList
+	maintenance *maintenance.Mode
+	reloads     int
+}
+
+func (b *fakeBackend) Connections() []models.Connection {
+	return []models.Connection{{Id: 1, RemoteAddress: "10.0.0.1:50000", ConnectedAt: 1609459200}}
+}
+
+func (b *fakeBackend) LoginStats() map[string]uint64 {
+	return map[string]uint64{models.LoginOutcomeOk: 3}
+}
+
+func (b *fakeBackend) BanList() *banlist.BanList {
+	return b.banList
+}
+
+func (b *fakeBackend) Maintenance() maintenance.Status {
+	return b.maintenance.Status(time.Now())
+}
+
+func (b *fakeBackend) SetMaintenance(enabled bool, backAt string) error {
+	return b.maintenance.Set(enabled, backAt)
+}
+
+func (b *fakeBackend) ReloadConfig() error {
+	b.reloads++
+	return nil
+}
+
+func (b *fakeBackend) Config() config.Config {
+	return config.Config{Database: config.DatabaseConfig{Password: "secret"}, Admin: config.Admin{Token: "token"}}
+}
+
+func doRequest(server *Server, method string, path string, body string, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	server := NewServer(&fakeBackend{banList: banlist.New()}, "token")
+
+	for _, token := range []string{"", "wrong"} {
+		response := doRequest(server, http.MethodGet, "/stats", "", token)
+		if response.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d for token %q, got %d", http.StatusUnauthorized, token, response.Code)
+		}
+	}
+}
+
+func TestAdminConnectionsAndStats(t *testing.T) {
+	server := NewServer(&fakeBackend{banList: banlist.New()}, "token")
+
+	response := doRequest(server, http.MethodGet, "/connections", "", "token")
+	var connections []connectionResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &connections); err != nil {
+		t.Fatalf("Failed to decode connections: %v", err)
+	}
+
+	if len(connections) != 1 || connections[0].RemoteAddress != "10.0.0.1:50000" {
+		t.Errorf("Unexpected connections: %+v", connections)
+	}
+
+	response = doRequest(server, http.MethodGet, "/stats", "", "token")
+	var stats map[string]uint64
+	if err := json.Unmarshal(response.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+
+	if stats[models.LoginOutcomeOk] != 3 {
+		t.Errorf("Expected 3 successful logins, got %v", stats)
+	}
+}
+
+func TestAdminBans(t *testing.T) {
+	backend := &fakeBackend{banList: banlist.New()}
+	server := NewServer(backend, "token")
+
+	response := doRequest(server, http.MethodPost, "/bans", `{"ip": "10.0.0.1", "reason": "spam", "duration": "1h"}`, "token")
+	if response.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, response.Code, response.Body.String())
+	}
+
+	if !backend.banList.GetIpBanInfo(16777226).IsBanned {
+		t.Error("Expected IP to be banned")
+	}
+
+	response = doRequest(server, http.MethodGet, "/bans", "", "token")
+	var bans []banResponse
+	if err := json.Unmarshal(response.Body.Bytes(), &bans); err != nil {
+		t.Fatalf("Failed to decode bans: %v", err)
+	}
+
+	if len(bans) != 1 || bans[0].Ip != "10.0.0.1" || bans[0].Reason != "spam" {
+		t.Errorf("Unexpected bans: %+v", bans)
+	}
+
+	response = doRequest(server, http.MethodDelete, "/bans/10.0.0.1", "", "token")
+	if response.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, response.Code)
+	}
+
+	response = doRequest(server, http.MethodDelete, "/bans/10.0.0.1", "", "token")
+	if response.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, response.Code)
+	}
+
+	response = doRequest(server, http.MethodPost, "/bans", `{"ip": "10.0.0.1", "duration": "forever"}`, "token")
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid duration, got %d", http.StatusBadRequest, response.Code)
+	}
+}
+
+func TestAdminMaintenanceAndReload(t *testing.T) {
+	backend := &fakeBackend{banList: banlist.New(), maintenance: maintenance.New()}
+	server := NewServer(backend, "token")
+
+	response := doRequest(server, http.MethodPut, "/maintenance", `{"enabled": true, "back_at": "14:30"}`, "token")
+	if status := backend.maintenance.Status(time.Now()); !status.Active || status.BackAt != "14:30" {
+		t.Errorf("Expected maintenance mode to be enabled until 14:30, got %+v", status)
+	}
+
+	response = doRequest(server, http.MethodGet, "/maintenance", "", "token")
+	if !strings.Contains(response.Body.String(), `"back_at":"14:30"`) {
+		t.Errorf("Expected maintenance status with back at time, got %s", response.Body.String())
+	}
+
+	response = doRequest(server, http.MethodPut, "/maintenance", `{"enabled": true, "back_at": "soon"}`, "token")
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid back at time, got %d", http.StatusBadRequest, response.Code)
+	}
+
+	response = doRequest(server, http.MethodPost, "/reload", "", "token")
+	if response.Code != http.StatusNoContent || backend.reloads != 1 {
+		t.Errorf("Expected config to be reloaded once, got status %d and %d reloads", response.Code, backend.reloads)
+	}
+}
+
+func TestAdminConfigIsMasked(t *testing.T) {
+	server := NewServer(&fakeBackend{banList: banlist.New()}, "token")
+
+	response := doRequest(server, http.MethodGet, "/config", "", "token")
+	if bytes.Contains(response.Body.Bytes(), []byte("secret")) {
+		t.Errorf("Expected database password to be masked, got %s", response.Body.String())
+	}
+}
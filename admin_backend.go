@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"go-opentibia-loginserver/banlist"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/maintenance"
+	"go-opentibia-loginserver/models"
+	"log/slog"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// LoginServer implements admin.Backend, these methods are called from the admin API goroutines
+
+func (s *LoginServer) Connections() []models.Connection {
+	var connections []models.Connection
+
+	s.connections.Range(func(key, value any) bool {
+		connections = append(connections, value.(models.Connection))
+		return true
+	})
+
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].Id < connections[j].Id
+	})
+
+	return connections
+}
+
+func (s *LoginServer) LoginStats() map[string]uint64 {
+	s.loginStatsMutex.Lock()
+	defer s.loginStatsMutex.Unlock()
+
+	stats := make(map[string]uint64, len(s.loginStats))
+	for outcome, count := range s.loginStats {
+		stats[outcome] = count
+	}
+
+	return stats
+}
+
+func (s *LoginServer) recordLoginOutcome(outcome string) {
+	s.loginStatsMutex.Lock()
+	defer s.loginStatsMutex.Unlock()
+
+	s.loginStats[outcome]++
+}
+
+func (s *LoginServer) BanList() *banlist.BanList {
+	return s.banList
+}
+
+func (s *LoginServer) Maintenance() maintenance.Status {
+	return s.maintenance.Status(time.Now())
+}
+
+func (s *LoginServer) SetMaintenance(enabled bool, backAt string) error {
+	return s.maintenance.Set(enabled, backAt)
+}
+
+func (s *LoginServer) Config() config.Config {
+	return *s.config.Load()
+}
+
+// ReloadConfig reads config.yaml again and applies it to new connections, keeping the current config
+// when the new one is invalid. Settings used to build the server at startup (listeners, database, keys,
+// password scheme, audit) keep their current values.
+func (s *LoginServer) ReloadConfig() error {
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	loaded, err := config.ReloadConfig()
+	if err != nil {
+		return fmt.Errorf("could not reload config: %w", err)
+	}
+
+	if err := validateConfig(&loaded); err != nil {
+		return fmt.Errorf("invalid config, keeping the current one: %w", err)
+	}
+
+	current := s.config.Load()
+	changedSections := changedStartupSections(&loaded, current)
+
+	loaded.LoginServer = current.LoginServer
+	loaded.Database = current.Database
+	loaded.RSAKeyFile = current.RSAKeyFile
+	loaded.RSAKeys = current.RSAKeys
+	loaded.QueryVersion = current.QueryVersion
+	loaded.AccountsFile = current.AccountsFile
+	loaded.PasswordScheme = current.PasswordScheme
+	loaded.PasswordRehash = current.PasswordRehash
+	loaded.LoginAudit = current.LoginAudit
+	loaded.UpdateLastLogin = current.UpdateLastLogin
+	loaded.Metrics = current.Metrics
+	loaded.Log = current.Log
+	loaded.Admin = current.Admin
+	loaded.WatchConfig = current.WatchConfig
+	loaded.MotdIdFile = current.MotdIdFile
+	loaded.WorldHealth = current.WorldHealth
+	loaded.Cast = current.Cast
+	loaded.Cam = current.Cam
+
+	motdProvider, err := createMotdProvider(&loaded, s.databaseQuery, s.db, s.motdIds)
+	if err != nil {
+		return fmt.Errorf("invalid config, keeping the current one: %w", err)
+	}
+
+	maintenanceSchedule, err := createMaintenanceSchedule(&loaded)
+	if err != nil {
+		return fmt.Errorf("invalid config, keeping the current one: %w", err)
+	}
+
+	// the maintenance switch may have been changed by a signal or the admin API since, only a changed config overrides it
+	if loaded.Maintenance.Enabled != current.Maintenance.Enabled || loaded.Maintenance.BackAt != current.Maintenance.BackAt {
+		if err := s.maintenance.Set(loaded.Maintenance.Enabled, loaded.Maintenance.BackAt); err != nil {
+			return fmt.Errorf("invalid config, keeping the current one: %w", err)
+		}
+		slog.Info("maintenance mode changed", "enabled", loaded.Maintenance.Enabled, "back_at", loaded.Maintenance.BackAt)
+	}
+
+	s.config.Store(&loaded)
+	s.motdProvider.Store(motdProvider)
+	s.maintenance.SetSchedule(maintenanceSchedule)
+
+	for _, section := range changedSections {
+		slog.Warn("config section changed, restart the server to apply it", "section", section)
+	}
+
+	slog.Info("config reloaded", "worlds", len(loaded.GameServer.Worlds))
+	return nil
+}
+
+// changedStartupSections lists the settings ReloadConfig keeps from the current config that differ in loaded
+func changedStartupSections(loaded *config.Config, current *config.Config) []string {
+	sections := []struct {
+		name    string
+		loaded  any
+		current any
+	}{
+		{"loginserver", loaded.LoginServer, current.LoginServer},
+		{"database", loaded.Database, current.Database},
+		{"rsakeyfile", loaded.RSAKeyFile, current.RSAKeyFile},
+		{"rsakeys", loaded.RSAKeys, current.RSAKeys},
+		{"queryversion", loaded.QueryVersion, current.QueryVersion},
+		{"accountsfile", loaded.AccountsFile, current.AccountsFile},
+		{"passwordscheme", loaded.PasswordScheme, current.PasswordScheme},
+		{"passwordrehash", loaded.PasswordRehash, current.PasswordRehash},
+		{"loginaudit", loaded.LoginAudit, current.LoginAudit},
+		{"updatelastlogin", loaded.UpdateLastLogin, current.UpdateLastLogin},
+		{"metrics", loaded.Metrics, current.Metrics},
+		{"log", loaded.Log, current.Log},
+		{"admin", loaded.Admin, current.Admin},
+		{"watchconfig", loaded.WatchConfig, current.WatchConfig},
+		{"motdidfile", loaded.MotdIdFile, current.MotdIdFile},
+		{"worldhealth", loaded.WorldHealth, current.WorldHealth},
+		{"cast", loaded.Cast, current.Cast},
+		{"cam", loaded.Cam, current.Cam},
+	}
+
+	var changed []string
+	for _, section := range sections {
+		if !reflect.DeepEqual(section.loaded, section.current) {
+			changed = append(changed, section.name)
+		}
+	}
+
+	return changed
+}
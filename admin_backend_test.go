@@ -0,0 +1,29 @@
+package main
+
+import (
+	"go-opentibia-loginserver/config"
+	"slices"
+	"testing"
+)
+
+func TestChangedStartupSections(t *testing.T) {
+	current := config.Config{
+		Cam:         config.Cam{Enabled: true, Limit: 15},
+		RSAKeys:     []config.RSAKey{{File: "old.pem"}},
+		WorldHealth: config.WorldHealth{Enabled: true},
+	}
+
+	loaded := current
+	loaded.RSAKeys = []config.RSAKey{{File: "old.pem"}}
+	loaded.Motd = "reloadable"
+	if changed := changedStartupSections(&loaded, &current); len(changed) != 0 {
+		t.Errorf("Expected no changed startup section, got %v", changed)
+	}
+
+	loaded.Cam.Limit = 10
+	loaded.RSAKeys = append(loaded.RSAKeys, config.RSAKey{File: "new.pem"})
+	loaded.Admin.Port = 9173
+	if changed := changedStartupSections(&loaded, &current); !slices.Equal(changed, []string{"rsakeys", "admin", "cam"}) {
+		t.Errorf("Expected rsakeys, admin and cam to be changed, got %v", changed)
+	}
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"go-opentibia-loginserver/logging"
+	"go-opentibia-loginserver/models"
+	"log/slog"
+	"sync"
+)
+
+const DefaultQueueSize = 1024
+
+// Sink stores login attempts, it is only called from the audit logger goroutine
+type Sink interface {
+	Write(attempt models.LoginAttempt) error
+	Close() error
+}
+
+// Logger queues login attempts and writes them to a sink in the background, so the login path never waits for it
+type Logger struct {
+	sink   Sink
+	queue  chan models.LoginAttempt
+	done   chan struct{}
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewLogger(sink Sink, queueSize int) *Logger {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	logger := &Logger{
+		sink:  sink,
+		queue: make(chan models.LoginAttempt, queueSize),
+		done:  make(chan struct{}),
+	}
+
+	go logger.run()
+	return logger
+}
+
+// Log queues an attempt; it is dropped when the queue is full or the logger is closed. A nil logger discards everything.
+func (l *Logger) Log(attempt models.LoginAttempt) {
+	if l == nil {
+		return
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		slog.Warn("login audit is closed, dropping login attempt", logging.KeyAccount, attempt.AccountNumber)
+		return
+	}
+
+	select {
+	case l.queue <- attempt:
+	default:
+		slog.Warn("login audit queue is full, dropping login attempt", logging.KeyAccount, attempt.AccountNumber)
+	}
+}
+
+// Close writes the queued attempts and closes the sink
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	l.closed = true
+	close(l.queue)
+	l.mu.Unlock()
+
+	<-l.done
+	return l.sink.Close()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	for attempt := range l.queue {
+		if err := l.sink.Write(attempt); err != nil {
+			slog.Error("could not write login attempt", logging.KeyError, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"go-opentibia-loginserver/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoggerWritesToFileSink(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "login_attempts.jsonl")
+
+	sink, err := NewFileSink(filename)
+	if err != nil {
+		t.Fatalf("Failed to create file sink: %v", err)
+	}
+
+	logger := NewLogger(sink, 4)
+	logger.Log(models.LoginAttempt{Timestamp: 1609459200, Ip: 16777226, AccountNumber: 123, ClientVersion: 772, ClientOs: 2, Outcome: models.LoginOutcomeOk})
+	logger.Log(models.LoginAttempt{Timestamp: 1609459201, Ip: 16777226, AccountNumber: 123, ClientVersion: 772, ClientOs: 2, Outcome: models.LoginOutcomeWrongPassword})
+
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Expected no error when closing logger, got: %v", err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Failed to open audit file: %v", err)
+	}
+	defer file.Close()
+
+	var records []fileRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record fileRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to decode audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	want := fileRecord{Timestamp: "2021-01-01T00:00:00Z", Ip: "10.0.0.1", AccountNumber: 123, ClientVersion: 772, ClientOs: 2, Outcome: models.LoginOutcomeOk}
+	if records[0] != want {
+		t.Errorf("got %+v, wanted %+v", records[0], want)
+	}
+
+	if records[1].Outcome != models.LoginOutcomeWrongPassword {
+		t.Errorf("Expected second outcome to be %s, got %s", models.LoginOutcomeWrongPassword, records[1].Outcome)
+	}
+}
+
+func TestNilLoggerIsNoop(t *testing.T) {
+	var logger *Logger
+
+	logger.Log(models.LoginAttempt{AccountNumber: 1})
+	if err := logger.Close(); err != nil {
+		t.Errorf("Expected no error when closing nil logger, got: %v", err)
+	}
+}
+
+func TestLogAfterCloseIsDropped(t *testing.T) {
+	sink, err := NewFileSink(filepath.Join(t.TempDir(), "login_attempts.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to create file sink: %v", err)
+	}
+
+	logger := NewLogger(sink, 4)
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Expected no error when closing logger, got: %v", err)
+	}
+
+	// connections still open at shutdown log after the logger is closed
+	logger.Log(models.LoginAttempt{AccountNumber: 1})
+}
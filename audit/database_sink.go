@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"database/sql"
+	"go-opentibia-loginserver/database"
+	"go-opentibia-loginserver/models"
+)
+
+// DatabaseSink stores login attempts through the query version, in its login attempts table
+type DatabaseSink struct {
+	recorder database.LoginAttemptRecorder
+	db       *sql.DB
+}
+
+func NewDatabaseSink(recorder database.LoginAttemptRecorder, db *sql.DB) *DatabaseSink {
+	return &DatabaseSink{recorder: recorder, db: db}
+}
+
+func (s *DatabaseSink) Write(attempt models.LoginAttempt) error {
+	return s.recorder.InsertLoginAttempt(s.db, attempt)
+}
+
+func (s *DatabaseSink) Close() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/utils"
+	"os"
+	"time"
+)
+
+// FileSink appends login attempts to a file, one JSON object per line
+type FileSink struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+type fileRecord struct {
+	Timestamp     string `json:"timestamp"`
+	Ip            string `json:"ip"`
+	AccountNumber uint32 `json:"account"`
+	ClientVersion uint16 `json:"client_version"`
+	ClientOs      uint16 `json:"client_os"`
+	Outcome       string `json:"outcome"`
+}
+
+func NewFileSink(filename string) (*FileSink, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+
+	return &FileSink{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+func (s *FileSink) Write(attempt models.LoginAttempt) error {
+	return s.encoder.Encode(fileRecord{
+		Timestamp:     time.Unix(attempt.Timestamp, 0).UTC().Format(time.RFC3339),
+		Ip:            utils.Uint32ToIp(attempt.Ip).String(),
+		AccountNumber: attempt.AccountNumber,
+		ClientVersion: attempt.ClientVersion,
+		ClientOs:      attempt.ClientOs,
+		Outcome:       attempt.Outcome,
+	})
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,108 @@
+package balancer
+
+import (
+	"encoding/binary"
+	"go-opentibia-loginserver/config"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	StrategyRoundRobin  = "roundrobin"
+	StrategyLeastRecent = "leastrecent"
+	StrategyIpHash      = "iphash"
+)
+
+// Balancer spreads logins between the gateways of each world, skipping the ones reported offline
+type Balancer struct {
+	isOnline func(address string) bool
+
+	mu       sync.Mutex
+	next     map[int]uint64
+	lastUsed map[string]time.Time
+}
+
+// New creates a balancer, isOnline is asked about every gateway address on each selection
+func New(isOnline func(address string) bool) *Balancer {
+	return &Balancer{
+		isOnline: isOnline,
+		next:     make(map[int]uint64),
+		lastUsed: make(map[string]time.Time),
+	}
+}
+
+// Select returns the world address advertised to a client. An online address rule matching the client network
+// wins, otherwise a gateway is chosen by the world balance strategy. It returns false when every gateway is offline.
+func (b *Balancer) Select(world *config.World, clientIp uint32) (uint32, uint16, bool) {
+	if gateway, found := world.GetNetworkGatewayFor(clientIp); found && b.isOnline(gateway.Address()) {
+		return gateway.HostIP, gateway.Port, true
+	}
+
+	var online []config.WorldGateway
+	for _, gateway := range world.GetGateways() {
+		if b.isOnline(gateway.Address()) {
+			online = append(online, gateway)
+		}
+	}
+
+	if len(online) == 0 {
+		return 0, 0, false
+	}
+
+	var gateway config.WorldGateway
+	switch world.Balance {
+	case StrategyLeastRecent:
+		gateway = b.leastRecent(online)
+	case StrategyIpHash:
+		gateway = ipHash(online, clientIp)
+	default:
+		gateway = b.roundRobin(world.ID, online)
+	}
+
+	return gateway.HostIP, gateway.Port, true
+}
+
+func (b *Balancer) roundRobin(worldId int, gateways []config.WorldGateway) config.WorldGateway {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	next := b.next[worldId]
+	b.next[worldId] = next + 1
+
+	return gateways[next%uint64(len(gateways))]
+}
+
+func (b *Balancer) leastRecent(gateways []config.WorldGateway) config.WorldGateway {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	selected := gateways[0]
+	for _, gateway := range gateways[1:] {
+		if b.lastUsed[gateway.Address()].Before(b.lastUsed[selected.Address()]) {
+			selected = gateway
+		}
+	}
+	b.lastUsed[selected.Address()] = time.Now()
+
+	return selected
+}
+
+// ipHash keeps a client on the same gateway using rendezvous hashing, so only the clients of a gateway
+// that went offline are moved to another one
+func ipHash(gateways []config.WorldGateway, clientIp uint32) config.WorldGateway {
+	var selected config.WorldGateway
+	var selectedScore uint64
+
+	for i, gateway := range gateways {
+		hash := fnv.New64a()
+		binary.Write(hash, binary.LittleEndian, clientIp)
+		hash.Write([]byte(gateway.Address()))
+
+		if score := hash.Sum64(); i == 0 || score > selectedScore {
+			selected, selectedScore = gateway, score
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,156 @@
+package balancer
+
+import (
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/utils"
+	"testing"
+)
+
+func newWorld(balance string) *config.World {
+	return &config.World{
+		Name:    "Test",
+		Port:    7172,
+		Balance: balance,
+		Gateways: []config.WorldGateway{
+			{HostName: "10.0.0.1", Port: 7172, HostIP: 1},
+			{HostName: "10.0.0.2", Port: 7172, HostIP: 2},
+			{HostName: "10.0.0.3", Port: 7172, HostIP: 3},
+		},
+	}
+}
+
+func allOnline(address string) bool {
+	return true
+}
+
+func TestRoundRobin(t *testing.T) {
+	balancer := New(allOnline)
+	world := newWorld(StrategyRoundRobin)
+
+	for _, expected := range []uint32{1, 2, 3, 1} {
+		ip, _, ok := balancer.Select(world, 0)
+		if !ok || ip != expected {
+			t.Errorf("Expected gateway %d, got %d (ok %v)", expected, ip, ok)
+		}
+	}
+}
+
+func TestLeastRecent(t *testing.T) {
+	balancer := New(allOnline)
+	world := newWorld(StrategyLeastRecent)
+
+	seen := make(map[uint32]bool)
+	for i := 0; i < 3; i++ {
+		ip, _, _ := balancer.Select(world, 0)
+		seen[ip] = true
+	}
+
+	if len(seen) != 3 {
+		t.Errorf("Expected every gateway to be used once, got %v", seen)
+	}
+}
+
+func TestIpHashIsSticky(t *testing.T) {
+	offline := ""
+	balancer := New(func(address string) bool { return address != offline })
+	world := newWorld(StrategyIpHash)
+
+	clientIp, _ := utils.IpToUint32("203.0.113.7")
+	first, _, _ := balancer.Select(world, clientIp)
+	for i := 0; i < 5; i++ {
+		if ip, _, _ := balancer.Select(world, clientIp); ip != first {
+			t.Fatalf("Expected client to stay on gateway %d, got %d", first, ip)
+		}
+	}
+
+	offline = world.Gateways[first-1].Address()
+	if ip, _, ok := balancer.Select(world, clientIp); !ok || ip == first {
+		t.Errorf("Expected client to move away from offline gateway %d, got %d (ok %v)", first, ip, ok)
+	}
+}
+
+func TestSkipsOfflineGateways(t *testing.T) {
+	balancer := New(func(address string) bool { return address == "10.0.0.2:7172" })
+	world := newWorld(StrategyRoundRobin)
+
+	for i := 0; i < 3; i++ {
+		if ip, _, _ := balancer.Select(world, 0); ip != 2 {
+			t.Errorf("Expected the only online gateway 2, got %d", ip)
+		}
+	}
+
+	balancer = New(func(address string) bool { return false })
+	if _, _, ok := balancer.Select(world, 0); ok {
+		t.Error("Expected no address when every gateway is offline")
+	}
+}
+
+func TestWithoutGatewaysUsesWorldAddress(t *testing.T) {
+	balancer := New(allOnline)
+	world := &config.World{Name: "Test", HostName: "10.0.0.9", Port: 7172, HostIP: 9}
+
+	if ip, port, ok := balancer.Select(world, 0); !ok || ip != 9 || port != 7172 {
+		t.Errorf("Expected the world address, got %d:%d (ok %v)", ip, port, ok)
+	}
+}
+
+func TestNetworkRuleFollowsHealth(t *testing.T) {
+	world := newWorld(StrategyRoundRobin)
+	world.Addresses = []config.WorldAddress{{Network: "192.168.0.0/16", HostName: "192.168.0.10"}}
+	if err := world.ParseAddresses(); err != nil {
+		t.Fatalf("Failed to parse addresses: %v", err)
+	}
+
+	lanIp, _ := utils.IpToUint32("192.168.1.20")
+	ruleIp, _ := utils.IpToUint32("192.168.0.10")
+
+	if ip, port, ok := New(allOnline).Select(world, lanIp); !ok || ip != ruleIp || port != 7172 {
+		t.Errorf("Expected the address rule for a LAN client, got %d:%d (ok %v)", ip, port, ok)
+	}
+
+	ruleOffline := New(func(address string) bool { return address != "192.168.0.10:7172" })
+	if ip, _, ok := ruleOffline.Select(world, lanIp); !ok || ip == ruleIp {
+		t.Errorf("Expected an online gateway when the address rule is offline, got %d (ok %v)", ip, ok)
+	}
+
+	if _, _, ok := New(func(address string) bool { return false }).Select(world, lanIp); ok {
+		t.Error("Expected no address when the address rule and every gateway are offline")
+	}
+}
+
+func TestNetworkRules(t *testing.T) {
+	worldIp, _ := utils.IpToUint32("200.200.200.200")
+	world := &config.World{
+		Name:     "Test",
+		HostName: "200.200.200.200",
+		Port:     7172,
+		HostIP:   worldIp,
+		Addresses: []config.WorldAddress{
+			{Network: "192.168.0.0/16", HostName: "192.168.0.10", Port: 7272},
+			{Network: "10.0.0.0/8", HostName: "10.0.0.10"},
+		},
+	}
+	if err := world.ParseAddresses(); err != nil {
+		t.Fatalf("Failed to parse addresses: %v", err)
+	}
+
+	tests := []struct {
+		clientIp     string
+		expectedIp   string
+		expectedPort uint16
+	}{
+		{"192.168.1.1", "192.168.0.10", 7272},
+		{"10.1.2.3", "10.0.0.10", 7172},
+		{"8.8.8.8", "200.200.200.200", 7172},
+	}
+
+	balancer := New(allOnline)
+	for _, test := range tests {
+		clientIp, _ := utils.IpToUint32(test.clientIp)
+		ip, port, ok := balancer.Select(world, clientIp)
+
+		if !ok || utils.Uint32ToIp(ip).String() != test.expectedIp || port != test.expectedPort {
+			t.Errorf("Expected %s:%d for client %s, got %s:%d (ok %v)", test.expectedIp, test.expectedPort, test.clientIp, utils.Uint32ToIp(ip), port, ok)
+		}
+	}
+}
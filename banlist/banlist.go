@@ -0,0 +1,82 @@
+package banlist
+
+import (
+	"go-opentibia-loginserver/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BanList keeps temporary IP bans in memory, next to the bans stored in the database
+type BanList struct {
+	mu   sync.Mutex
+	bans map[uint32]Ban
+}
+
+type Ban struct {
+	Ip        uint32
+	Reason    string
+	Author    string
+	ExpiresAt int64
+}
+
+func New() *BanList {
+	return &BanList{bans: make(map[uint32]Ban)}
+}
+
+func (b *BanList) Add(ban Ban) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bans[ban.Ip] = ban
+}
+
+// Remove lifts the ban of an IP, returning false when it was not banned
+func (b *BanList) Remove(ip uint32) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, found := b.bans[ip]
+	delete(b.bans, ip)
+	return found
+}
+
+func (b *BanList) GetIpBanInfo(ip uint32) models.BanInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ban, found := b.bans[ip]
+	if !found {
+		return models.BanInfo{}
+	}
+
+	if ban.ExpiresAt <= time.Now().Unix() {
+		delete(b.bans, ip)
+		return models.BanInfo{}
+	}
+
+	return models.BanInfo{Author: ban.Author, Reason: ban.Reason, ExpiresAt: ban.ExpiresAt, IsBanned: true}
+}
+
+// List returns the bans which did not expire yet, ordered by expiration
+func (b *BanList) List() []Ban {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now().Unix()
+	bans := make([]Ban, 0, len(b.bans))
+
+	for ip, ban := range b.bans {
+		if ban.ExpiresAt <= now {
+			delete(b.bans, ip)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].ExpiresAt < bans[j].ExpiresAt
+	})
+
+	return bans
+}
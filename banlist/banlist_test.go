@@ -0,0 +1,56 @@
+package banlist
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBanListAddAndRemove(t *testing.T) {
+	banList := New()
+	banList.Add(Ban{Ip: 16777226, Reason: "spam", Author: "admin", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	banInfo := banList.GetIpBanInfo(16777226)
+	if !banInfo.IsBanned {
+		t.Fatal("Expected IP to be banned")
+	}
+
+	if banInfo.Reason != "spam" || banInfo.Author != "admin" {
+		t.Errorf("Expected ban reason spam by admin, got %s by %s", banInfo.Reason, banInfo.Author)
+	}
+
+	if banList.GetIpBanInfo(16885952).IsBanned {
+		t.Error("Expected other IP not to be banned")
+	}
+
+	if !banList.Remove(16777226) {
+		t.Error("Expected remove to find the ban")
+	}
+
+	if banList.GetIpBanInfo(16777226).IsBanned {
+		t.Error("Expected IP not to be banned after removal")
+	}
+
+	if banList.Remove(16777226) {
+		t.Error("Expected second remove not to find the ban")
+	}
+}
+
+func TestBanListExpiredBans(t *testing.T) {
+	banList := New()
+	banList.Add(Ban{Ip: 1, ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	banList.Add(Ban{Ip: 2, ExpiresAt: time.Now().Add(2 * time.Hour).Unix()})
+	banList.Add(Ban{Ip: 3, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	if banList.GetIpBanInfo(1).IsBanned {
+		t.Error("Expected expired ban to be ignored")
+	}
+
+	bans := banList.List()
+	if len(bans) != 2 {
+		t.Fatalf("Expected 2 active bans, got %d", len(bans))
+	}
+
+	if bans[0].Ip != 3 || bans[1].Ip != 2 {
+		t.Errorf("Expected bans ordered by expiration, got %+v", bans)
+	}
+}
@@ -0,0 +1,126 @@
+package cam
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-opentibia-loginserver/database"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/protocol"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	SourceDirectory = "directory"
+	SourceDatabase  = "database"
+
+	// IndexFilename is the file listing the recordings of a cam directory
+	IndexFilename = "index.json"
+
+	// MaxEntries is the most entries a character list can count. Fewer fit in the login packet, 15 to 20
+	// depending on the player names, and the rest are left out when the list is sent, see protocol.FitCharacters.
+	MaxEntries = protocol.MaxCharacters
+
+	// DefaultWorldName is shown next to the entries when no world name is configured
+	DefaultWorldName = "Cam"
+)
+
+// Provider lists the recordings available on the cam playback server
+type Provider interface {
+	GetRecordings() ([]models.CamRecording, error)
+}
+
+type indexEntry struct {
+	Id         uint32 `json:"id"`
+	PlayerName string `json:"player_name"`
+	RecordedAt int64  `json:"recorded_at"`
+	Duration   uint32 `json:"duration"`
+}
+
+// DirectoryProvider reads the index of the directory where the playback server keeps its recordings on every
+// call, so new recordings are listed right away
+type DirectoryProvider struct {
+	directory string
+}
+
+func NewDirectoryProvider(directory string) *DirectoryProvider {
+	return &DirectoryProvider{directory: directory}
+}
+
+func (p *DirectoryProvider) GetRecordings() ([]models.CamRecording, error) {
+	filename := filepath.Join(p.directory, IndexFilename)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cam index: %w", err)
+	}
+
+	var entries []indexEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("could not parse cam index %s: %w", filename, err)
+	}
+
+	recordings := make([]models.CamRecording, 0, len(entries))
+	for _, entry := range entries {
+		recordings = append(recordings, models.CamRecording(entry))
+	}
+
+	return recordings, nil
+}
+
+type DatabaseProvider struct {
+	query database.CamQuery
+	db    *sql.DB
+}
+
+func NewDatabaseProvider(query database.CamQuery, db *sql.DB) *DatabaseProvider {
+	return &DatabaseProvider{query: query, db: db}
+}
+
+func (p *DatabaseProvider) GetRecordings() ([]models.CamRecording, error) {
+	return p.query.GetCamRecordings(p.db)
+}
+
+// Entries returns the character list entries of the most recent recordings, at most limit of them
+func Entries(recordings []models.CamRecording, limit int) []string {
+	if limit <= 0 || limit > MaxEntries {
+		limit = MaxEntries
+	}
+
+	sorted := make([]models.CamRecording, len(recordings))
+	copy(sorted, recordings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].RecordedAt > sorted[j].RecordedAt
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+
+	entries := make([]string, 0, len(sorted))
+	for _, recording := range sorted {
+		entries = append(entries, EntryName(recording))
+	}
+
+	return entries
+}
+
+// EntryName encodes a recording as a character name, "#<id> <player> <date> <duration>". The playback server
+// receives it back as the character name and finds the recording by its id.
+func EntryName(recording models.CamRecording) string {
+	recordedAt := time.Unix(recording.RecordedAt, 0).UTC().Format("2006-01-02 15:04")
+	return fmt.Sprintf("#%d %s %s %s", recording.Id, recording.PlayerName, recordedAt, formatDuration(recording.Duration))
+}
+
+func formatDuration(seconds uint32) string {
+	minutes := (seconds + 30) / 60
+
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+
+	return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+}
@@ -0,0 +1,113 @@
+package cam
+
+import (
+	"go-opentibia-loginserver/client"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/protocol"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEntryName(t *testing.T) {
+	tests := []struct {
+		recording models.CamRecording
+		expected  string
+	}{
+		{models.CamRecording{Id: 12, PlayerName: "Knight", RecordedAt: 1709577000, Duration: 3900}, "#12 Knight 2024-03-04 18:30 1h05m"},
+		{models.CamRecording{Id: 3, PlayerName: "Druid", RecordedAt: 1709577000, Duration: 95}, "#3 Druid 2024-03-04 18:30 2m"},
+	}
+
+	for _, test := range tests {
+		if name := EntryName(test.recording); name != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, name)
+		}
+	}
+}
+
+func TestEntriesAreRecentFirstAndLimited(t *testing.T) {
+	recordings := []models.CamRecording{
+		{Id: 1, PlayerName: "Old", RecordedAt: 100},
+		{Id: 2, PlayerName: "New", RecordedAt: 300},
+		{Id: 3, PlayerName: "Middle", RecordedAt: 200},
+	}
+
+	entries := Entries(recordings, 2)
+	expected := []string{EntryName(recordings[1]), EntryName(recordings[2])}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %v, got %v", expected, entries)
+	}
+}
+
+func TestDirectoryProvider(t *testing.T) {
+	directory := t.TempDir()
+	index := `[{"id": 7, "player_name": "Paladin", "recorded_at": 1709577000, "duration": 600}]`
+	if err := os.WriteFile(filepath.Join(directory, IndexFilename), []byte(index), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	recordings, err := NewDirectoryProvider(directory).GetRecordings()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []models.CamRecording{{Id: 7, PlayerName: "Paladin", RecordedAt: 1709577000, Duration: 600}}
+	if !reflect.DeepEqual(recordings, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, recordings)
+	}
+
+	if _, err := NewDirectoryProvider(t.TempDir()).GetRecordings(); err == nil {
+		t.Error("Expected an error for a directory without index, got none")
+	}
+}
+
+// sendEntries sends entries as the character list of the playback world and parses what a client receives
+func sendEntries(t *testing.T, entries []string) *client.Response {
+	xteaKey := [4]uint32{1, 2, 3, 4}
+	world := &config.World{Name: DefaultWorldName}
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	go func() {
+		defer serverConn.Close()
+		protocol.SendClientMotdAndCharacterList(serverConn, xteaKey, models.Motd{}, &models.AccountInfo{Characters: entries}, world, 0x0100007F, 7175)
+	}()
+
+	response, err := client.ReadResponse(clientConn, xteaKey)
+	if err != nil {
+		t.Fatalf("Expected the client to parse the cam list, got: %v", err)
+	}
+
+	return response
+}
+
+func TestEntriesFitInLoginPacket(t *testing.T) {
+	recordings := make([]models.CamRecording, MaxEntries)
+	for i := range recordings {
+		recordings[i] = models.CamRecording{Id: uint32(1000 + i), PlayerName: "Knight of Thais", RecordedAt: 1709577000 + int64(i), Duration: 3900}
+	}
+
+	for _, limit := range []int{15, MaxEntries} {
+		entries := Entries(recordings, limit)
+		fit := protocol.FitCharacters(models.Motd{}, entries, DefaultWorldName)
+
+		response := sendEntries(t, entries)
+		if len(response.Characters) != fit || fit == 0 {
+			t.Fatalf("limit %d: expected the %d entries that fit, got %d", limit, fit, len(response.Characters))
+		}
+
+		for i, character := range response.Characters {
+			if character.Name != entries[i] {
+				t.Errorf("limit %d: expected entry %d to be %q, got %q", limit, i, entries[i], character.Name)
+			}
+		}
+	}
+
+	if fit := protocol.FitCharacters(models.Motd{}, Entries(recordings, 15), DefaultWorldName); fit != 15 {
+		t.Errorf("Expected the example limit of 15 entries to fit, got %d", fit)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"go-opentibia-loginserver/cam"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/logging"
+	"go-opentibia-loginserver/metrics"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/protocol"
+	"go-opentibia-loginserver/utils"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// handleCamLogin answers the viewer account with the recorded cams as characters of the playback server.
+// It returns the login outcome.
+func (s *LoginServer) handleCamLogin(conn net.Conn, loginInfo *protocol.LoginRequest, cfg *config.Config, logger *slog.Logger) string {
+	if status := s.maintenance.Status(time.Now()); status.Active {
+		protocol.SendClientError(conn, loginInfo.XteaKey, status.Message(cfg.Maintenance.Message))
+		return models.LoginOutcomeMaintenance
+	}
+
+	start := time.Now()
+	recordings, err := s.camProvider.GetRecordings()
+	metrics.ObservePhase(metrics.PhaseCams, start)
+	if err != nil {
+		logger.Error("could not fetch cam recordings", logging.KeyError, err)
+		return models.LoginOutcomeDatabaseError
+	}
+
+	entries := cam.Entries(recordings, cfg.Cam.Limit)
+	if len(entries) == 0 {
+		protocol.SendClientError(conn, loginInfo.XteaKey, "There are no recorded cams at the moment.")
+		return models.LoginOutcomeEmptyList
+	}
+
+	//the address is checked by config validation
+	playbackIp, _ := utils.IpToUint32(cfg.Cam.HostName)
+	playbackWorld := config.World{Name: cfg.Cam.WorldName, HostName: cfg.Cam.HostName, Port: cfg.Cam.Port, HostIP: playbackIp}
+	if playbackWorld.Name == "" {
+		playbackWorld.Name = cam.DefaultWorldName
+	}
+	entries = entries[:protocol.FitCharacters(models.Motd{}, entries, playbackWorld.Name)]
+	accountInfo := models.AccountInfo{Characters: entries}
+
+	start = time.Now()
+	err = protocol.SendClientMotdAndCharacterList(conn, loginInfo.XteaKey, models.Motd{}, &accountInfo, &playbackWorld, playbackWorld.HostIP, playbackWorld.Port)
+	metrics.ObservePhase(metrics.PhaseSend, start)
+	if err != nil {
+		logger.Warn("could not send cam list", logging.KeyError, err)
+		return models.LoginOutcomeSendError
+	}
+
+	logger.Debug("sent cam recordings", "cams", len(entries))
+	return models.LoginOutcomeOk
+}
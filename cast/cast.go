@@ -0,0 +1,22 @@
+package cast
+
+import (
+	"crypto/subtle"
+	"go-opentibia-loginserver/models"
+)
+
+// VisibleTo returns the names of the casts a viewer can watch with password: every public cast, plus the
+// protected ones whose password matches
+func VisibleTo(casts []models.LiveCast, password string) []string {
+	var names []string
+
+	for _, cast := range casts {
+		if cast.Password != "" && subtle.ConstantTimeCompare([]byte(cast.Password), []byte(password)) != 1 {
+			continue
+		}
+
+		names = append(names, cast.PlayerName)
+	}
+
+	return names
+}
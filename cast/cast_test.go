@@ -0,0 +1,31 @@
+package cast
+
+import (
+	"go-opentibia-loginserver/models"
+	"reflect"
+	"testing"
+)
+
+func TestVisibleTo(t *testing.T) {
+	casts := []models.LiveCast{
+		{PlayerName: "Public", Spectators: 10},
+		{PlayerName: "Guild Only", Password: "secret", Spectators: 5},
+		{PlayerName: "Friends Only", Password: "friends"},
+	}
+
+	tests := []struct {
+		password string
+		expected []string
+	}{
+		{"", []string{"Public"}},
+		{"secret", []string{"Public", "Guild Only"}},
+		{"friends", []string{"Public", "Friends Only"}},
+		{"wrong", []string{"Public"}},
+	}
+
+	for _, test := range tests {
+		if names := VisibleTo(casts, test.password); !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("Expected %v for password %q, got %v", test.expected, test.password, names)
+		}
+	}
+}
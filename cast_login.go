@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"go-opentibia-loginserver/cast"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/database"
+	"go-opentibia-loginserver/logging"
+	"go-opentibia-loginserver/metrics"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/protocol"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// handleCastLogin answers the cast account with the live casts as characters, the password typed by the viewer
+// unlocks the protected casts. It returns the login outcome.
+func (s *LoginServer) handleCastLogin(conn net.Conn, loginInfo *protocol.LoginRequest, cfg *config.Config, remoteIpAddress uint32, logger *slog.Logger) string {
+	if status := s.maintenance.Status(time.Now()); status.Active {
+		protocol.SendClientError(conn, loginInfo.XteaKey, status.Message(cfg.Maintenance.Message))
+		return models.LoginOutcomeMaintenance
+	}
+
+	start := time.Now()
+	casts, err := s.databaseQuery.(database.CastQuery).GetLiveCasts(s.db)
+	metrics.ObservePhase(metrics.PhaseDbCasts, start)
+	if err != nil {
+		logger.Error("could not fetch live casts", logging.KeyError, err)
+		return models.LoginOutcomeDatabaseError
+	}
+
+	characters := cast.VisibleTo(casts, loginInfo.Password)
+	if len(characters) == 0 {
+		protocol.SendClientError(conn, loginInfo.XteaKey, "There are no live casts at the moment.")
+		return models.LoginOutcomeEmptyList
+	}
+
+	//casts are broadcast by the default world, as every character
+	world, err := config.GetDefaultWorld(cfg)
+	if err != nil {
+		logger.Error("could not get world", logging.KeyError, err)
+		return models.LoginOutcomeInternalError
+	}
+
+	worldIp, worldPort, online := s.balancer.Select(&world, remoteIpAddress)
+	if !online {
+		protocol.SendClientError(conn, loginInfo.XteaKey, fmt.Sprintf("World %s is currently offline, please try again later.", world.Name))
+		return models.LoginOutcomeWorldOffline
+	}
+
+	// casts are sorted by spectators, so the most watched ones are kept when the list does not fit
+	characters = characters[:protocol.FitCharacters(models.Motd{}, characters, world.Name)]
+	accountInfo := models.AccountInfo{Characters: characters}
+
+	start = time.Now()
+	err = protocol.SendClientMotdAndCharacterList(conn, loginInfo.XteaKey, models.Motd{}, &accountInfo, &world, worldIp, worldPort)
+	metrics.ObservePhase(metrics.PhaseSend, start)
+	if err != nil {
+		logger.Warn("could not send cast list", logging.KeyError, err)
+		return models.LoginOutcomeSendError
+	}
+
+	logger.Debug("sent live casts", "casts", len(characters))
+	return models.LoginOutcomeOk
+}
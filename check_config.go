@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/crypt"
+	"go-opentibia-loginserver/database"
+	"go-opentibia-loginserver/logging"
+	"go-opentibia-loginserver/maintenance"
+	"io"
+	"os"
+)
+
+// minListAccountNumber is the lowest safe account number for the cast and cam accounts, the first accounts of
+// a server usually belong to its staff and logging in with them would list casts or cams instead
+const minListAccountNumber = 100
+
+// runCheckConfig loads and validates the config without starting the server, printing every problem found
+func runCheckConfig(args []string) int {
+	flags := flag.NewFlagSet("check-config", flag.ExitOnError)
+	configFile := flags.String("config", "", "path to the config file (default: config.yaml in the search paths)")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Printf("could not load config: %s\n", err)
+		return 1
+	}
+
+	for _, warning := range configWarnings(&cfg) {
+		fmt.Printf("warning: %s\n", warning)
+	}
+
+	err = validateConfig(&cfg)
+	if err == nil {
+		fmt.Println("config is valid")
+		return 0
+	}
+
+	fmt.Println("config has problems:")
+	for _, problem := range splitErrors(err) {
+		fmt.Printf("  - %s\n", problem)
+	}
+
+	return 1
+}
+
+// validateConfig runs config.Validate and checks the settings only the packages using them know about: query
+// versions, password schemes, log options and maintenance times
+func validateConfig(cfg *config.Config) error {
+	problems := splitErrors(cfg.Validate())
+
+	// the file query version reads accountsfile and never connects to the database
+	if cfg.QueryVersion == database.QueryVersionFile {
+		if cfg.AccountsFile == "" {
+			problems = append(problems, fmt.Errorf("accountsfile: is required by queryversion %s", database.QueryVersionFile))
+		} else if _, err := os.Stat(cfg.AccountsFile); err != nil {
+			problems = append(problems, fmt.Errorf("accountsfile: %w", err))
+		}
+	} else {
+		if database.GetDatabaseQuery(cfg.QueryVersion) == nil {
+			problems = append(problems, fmt.Errorf("queryversion: unknown query version %q", cfg.QueryVersion))
+		}
+
+		if !config.IsValidPort(cfg.Database.Port) {
+			problems = append(problems, fmt.Errorf("database.port: %d is not a valid port", cfg.Database.Port))
+		}
+	}
+
+	if cfg.PasswordScheme != "" && crypt.GetPasswordVerifier(cfg.PasswordScheme) == nil {
+		problems = append(problems, fmt.Errorf("passwordscheme: unknown password scheme %q", cfg.PasswordScheme))
+	}
+
+	if cfg.PasswordRehash.Enabled {
+		if cfg.PasswordScheme != crypt.PasswordSchemeAuto {
+			problems = append(problems, fmt.Errorf("passwordrehash: requires passwordscheme %s", crypt.PasswordSchemeAuto))
+		}
+
+		if crypt.GetPasswordHasher(cfg.PasswordRehash.Scheme) == nil {
+			problems = append(problems, fmt.Errorf("passwordrehash.scheme: unsupported scheme %q", cfg.PasswordRehash.Scheme))
+		}
+	}
+
+	if cfg.Maintenance.BackAt != "" {
+		if _, err := maintenance.ParseClock(cfg.Maintenance.BackAt); err != nil {
+			problems = append(problems, fmt.Errorf("maintenance.backat: %w", err))
+		}
+	}
+
+	for i, window := range cfg.Maintenance.Schedule {
+		if _, err := maintenance.ParseWindow(window.From, window.Until); err != nil {
+			problems = append(problems, fmt.Errorf("maintenance.schedule[%d]: %w", i, err))
+		}
+	}
+
+	if _, err := logging.New(io.Discard, logging.Options{Level: cfg.Log.Level, Format: cfg.Log.Format}); err != nil {
+		problems = append(problems, fmt.Errorf("log: %w", err))
+	}
+
+	return errors.Join(problems...)
+}
+
+// configWarnings returns the settings that are valid but likely a mistake
+func configWarnings(cfg *config.Config) []string {
+	var warnings []string
+
+	// 0 is not a real account, so the cast account can safely use it
+	if cfg.Cast.Enabled && cfg.Cast.AccountNumber != 0 && cfg.Cast.AccountNumber < minListAccountNumber {
+		warnings = append(warnings, fmt.Sprintf("cast.accountnumber: %d may be a player or staff account, use %d or above", cfg.Cast.AccountNumber, minListAccountNumber))
+	}
+
+	if cfg.Cam.Enabled && cfg.Cam.AccountNumber < minListAccountNumber {
+		warnings = append(warnings, fmt.Sprintf("cam.accountnumber: %d may be a player or staff account, use %d or above", cfg.Cam.AccountNumber, minListAccountNumber))
+	}
+
+	return warnings
+}
+
+// splitErrors returns the errors joined in err, or err alone
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+
+	var joinedErrors interface{ Unwrap() []error }
+	if errors.As(err, &joinedErrors) {
+		return joinedErrors.Unwrap()
+	}
+
+	return []error{err}
+}
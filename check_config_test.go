@@ -0,0 +1,133 @@
+package main
+
+import (
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/database"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newValidTestConfig(t *testing.T) config.Config {
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, []byte("key"), 0644); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	return config.Config{
+		GameServer:   config.GameServer{Worlds: []config.World{{Name: "Test", HostName: "127.0.0.1", Port: 7172, HostIP: 0x0100007F}}},
+		LoginServer:  config.LoginServer{HostName: "localhost", Port: 7171},
+		Database:     config.DatabaseConfig{Port: 3306},
+		RSAKeyFile:   keyFile,
+		QueryVersion: "tvp",
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	cfg := newValidTestConfig(t)
+
+	if err := validateConfig(&cfg); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateConfigReportsEveryProblem(t *testing.T) {
+	cfg := newValidTestConfig(t)
+	cfg.LoginServer.Port = 70000
+	cfg.QueryVersion = "unknown"
+	cfg.Database.Port = 0
+	cfg.PasswordScheme = "rot13"
+	cfg.Log.Level = "verbose"
+	cfg.Maintenance.BackAt = "25:00"
+	cfg.Maintenance.Schedule = []config.MaintenanceWindow{{From: "05:50", Until: "6h"}}
+
+	err := validateConfig(&cfg)
+	if err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+
+	for _, want := range []string{
+		"loginserver.port",
+		"queryversion",
+		"database.port",
+		"passwordscheme",
+		"log:",
+		"maintenance.backat",
+		"maintenance.schedule[0]",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+
+	if problems := splitErrors(err); len(problems) != 7 {
+		t.Errorf("Expected 7 problems, got %d: %v", len(problems), problems)
+	}
+}
+
+func TestValidateConfigPasswordRehash(t *testing.T) {
+	cfg := newValidTestConfig(t)
+	cfg.PasswordRehash = config.PasswordRehash{Enabled: true, Scheme: "bcrypt"}
+
+	if err := validateConfig(&cfg); err == nil {
+		t.Error("Expected an error for password rehash without the auto scheme, got none")
+	}
+
+	cfg.PasswordScheme = "auto"
+	if err := validateConfig(&cfg); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateConfigFileQueryVersion(t *testing.T) {
+	cfg := newValidTestConfig(t)
+	cfg.QueryVersion = database.QueryVersionFile
+	cfg.Database = config.DatabaseConfig{}
+
+	err := validateConfig(&cfg)
+	if err == nil || !strings.Contains(err.Error(), "accountsfile") {
+		t.Errorf("Expected an error for a missing accounts file, got: %v", err)
+	}
+
+	cfg.AccountsFile = filepath.Join(t.TempDir(), "accounts.yaml")
+	if err := os.WriteFile(cfg.AccountsFile, []byte("accounts: []"), 0644); err != nil {
+		t.Fatalf("Failed to write accounts file: %v", err)
+	}
+
+	if err := validateConfig(&cfg); err != nil {
+		t.Errorf("Expected no error without database settings, got: %v", err)
+	}
+}
+
+func TestConfigWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		cast     config.Cast
+		cam      config.Cam
+		expected []string
+	}{
+		{"disabled", config.Cast{AccountNumber: 1}, config.Cam{AccountNumber: 1}, nil},
+		{"dedicated accounts", config.Cast{Enabled: true, AccountNumber: 0}, config.Cam{Enabled: true, AccountNumber: 999999}, nil},
+		{"low cast account", config.Cast{Enabled: true, AccountNumber: 1}, config.Cam{}, []string{"cast.accountnumber"}},
+		{"low cam account", config.Cast{}, config.Cam{Enabled: true, AccountNumber: 1}, []string{"cam.accountnumber"}},
+	}
+
+	for _, test := range tests {
+		cfg := newValidTestConfig(t)
+		cfg.Cast = test.cast
+		cfg.Cam = test.cam
+
+		warnings := configWarnings(&cfg)
+		if len(warnings) != len(test.expected) {
+			t.Errorf("%s: expected %d warnings, got %v", test.name, len(test.expected), warnings)
+			continue
+		}
+
+		for i, want := range test.expected {
+			if !strings.HasPrefix(warnings[i], want) {
+				t.Errorf("%s: expected a warning for %s, got %q", test.name, want, warnings[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package client
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"fmt"
+	"go-opentibia-loginserver/crypt"
+	"go-opentibia-loginserver/packet"
+	"go-opentibia-loginserver/protocol"
+	"go-opentibia-loginserver/utils"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultProtocolVersion uint16 = 772
+	DefaultClientOs        uint16 = 2 // windows
+	DefaultTimeout                = 5 * time.Second
+
+	opcodeLogin         uint8 = 0x01
+	opcodeError         uint8 = 0x0A
+	opcodeMotd          uint8 = 0x14
+	opcodeCharacterList uint8 = 0x64
+
+	// loginBlockHeaderSize is the zero byte, the XTEA key, the account number and the password length
+	loginBlockHeaderSize = 1 + 16 + 4 + 2
+)
+
+// Options describe the client that logs in, the public key is the one the client is patched with
+type Options struct {
+	Address         string
+	PublicKey       *rsa.PublicKey
+	ProtocolVersion uint16
+	ClientOs        uint16
+	Timeout         time.Duration
+}
+
+type Character struct {
+	Name  string
+	World string
+	Ip    uint32
+	Port  uint16
+}
+
+// Address is where the client connects to play the character
+func (c Character) Address() string {
+	return net.JoinHostPort(utils.Uint32ToIp(c.Ip).String(), strconv.Itoa(int(c.Port)))
+}
+
+// Response is what the login server answered, Error is set when the login was refused
+type Response struct {
+	Error       string
+	MotdId      uint32
+	Motd        string
+	Characters  []Character
+	PremiumDays uint16
+}
+
+// Login connects to the login server, logs in to the account and reads the answer.
+// A refused login is not an error, it is returned in Response.Error.
+func Login(options Options, accountNumber uint32, password string) (*Response, error) {
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	xteaKey, err := NewXteaKey()
+	if err != nil {
+		return nil, err
+	}
+
+	loginPacket, err := BuildLoginPacket(options.PublicKey, protocol.LoginRequest{
+		ClientOs:        withDefault(options.ClientOs, DefaultClientOs),
+		ProtocolVersion: withDefault(options.ProtocolVersion, DefaultProtocolVersion),
+		XteaKey:         xteaKey,
+		AccountNumber:   accountNumber,
+		Password:        password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("tcp", options.Address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	// the server reads the login in a single read, so it is sent in a single write
+	if _, err := conn.Write(loginPacket); err != nil {
+		return nil, fmt.Errorf("failed to send login packet: %w", err)
+	}
+
+	return ReadResponse(conn, xteaKey)
+}
+
+func withDefault(value uint16, defaultValue uint16) uint16 {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// NewXteaKey generates the random key the server encrypts its answer with
+func NewXteaKey() ([4]uint32, error) {
+	var key [4]uint32
+	if err := binary.Read(rand.Reader, binary.LittleEndian, &key); err != nil {
+		return key, fmt.Errorf("failed to generate XTEA key: %w", err)
+	}
+
+	return key, nil
+}
+
+// BuildLoginPacket builds the login packet a client sends, with its size header, for the fields of request.
+// The XTEA key, account number and password are RSA encrypted with publicKey.
+func BuildLoginPacket(publicKey *rsa.PublicKey, request protocol.LoginRequest) ([]byte, error) {
+	if publicKey == nil {
+		return nil, fmt.Errorf("no public key to encrypt the login with")
+	}
+
+	blockSize := publicKey.Size()
+	if loginBlockHeaderSize+len(request.Password) > blockSize {
+		return nil, fmt.Errorf("password of %d bytes does not fit in a %d-byte RSA block", len(request.Password), blockSize)
+	}
+
+	// the block starts with a zero byte, which keeps it below the modulus
+	block := make([]byte, blockSize)
+	for i, word := range request.XteaKey {
+		binary.LittleEndian.PutUint32(block[1+i*4:], word)
+	}
+	binary.LittleEndian.PutUint32(block[17:], request.AccountNumber)
+	binary.LittleEndian.PutUint16(block[21:], uint16(len(request.Password)))
+	copy(block[loginBlockHeaderSize:], request.Password)
+
+	encryptedBlock, err := crypt.EncryptNoPadding(publicKey, block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt login block: %w", err)
+	}
+
+	data := make([]byte, 19, 19+len(encryptedBlock))
+	binary.LittleEndian.PutUint16(data[0:], uint16(17+len(encryptedBlock)))
+	data[2] = opcodeLogin
+	binary.LittleEndian.PutUint16(data[3:], request.ClientOs)
+	binary.LittleEndian.PutUint16(data[5:], request.ProtocolVersion)
+	binary.LittleEndian.PutUint32(data[7:], request.DatSignature)
+	binary.LittleEndian.PutUint32(data[11:], request.SprSignature)
+	binary.LittleEndian.PutUint32(data[15:], request.PicSignature)
+
+	return append(data, encryptedBlock...), nil
+}
+
+// ReadResponse reads one packet sent by the login server and parses it
+func ReadResponse(reader io.Reader, xteaKey [4]uint32) (*Response, error) {
+	var size uint16
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, fmt.Errorf("failed to read response size: %w", err)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return ParseResponse(data, xteaKey)
+}
+
+// ParseResponse decrypts and parses a packet sent by the login server, without its size header
+func ParseResponse(data []byte, xteaKey [4]uint32) (*Response, error) {
+	incoming := packet.NewIncoming(len(data))
+	copy(incoming.PeekBuffer(), data)
+
+	if err := incoming.XteaDecrypt(xteaKey); err != nil {
+		return nil, err
+	}
+
+	response := &Response{}
+	for incoming.Remaining() > 0 {
+		opcode := incoming.GetUint8()
+
+		switch opcode {
+		case opcodeError:
+			message, err := getString(incoming)
+			if err != nil {
+				return nil, err
+			}
+			response.Error = message
+			return response, nil
+
+		case opcodeMotd:
+			motd, err := getString(incoming)
+			if err != nil {
+				return nil, err
+			}
+			response.MotdId, response.Motd = splitMotd(motd)
+
+		case opcodeCharacterList:
+			if err := parseCharacterList(incoming, response); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, fmt.Errorf("unexpected opcode 0x%02X in response", opcode)
+		}
+	}
+
+	return response, nil
+}
+
+func parseCharacterList(incoming *packet.Incoming, response *Response) error {
+	if incoming.Remaining() < 1 {
+		return errTruncated("character list")
+	}
+
+	count := int(incoming.GetUint8())
+	response.Characters = make([]Character, 0, count)
+
+	for i := 0; i < count; i++ {
+		var character Character
+		var err error
+
+		if character.Name, err = getString(incoming); err != nil {
+			return err
+		}
+
+		if character.World, err = getString(incoming); err != nil {
+			return err
+		}
+
+		if incoming.Remaining() < 6 {
+			return errTruncated("character address")
+		}
+		character.Ip = incoming.GetUint32()
+		character.Port = incoming.GetUint16()
+
+		response.Characters = append(response.Characters, character)
+	}
+
+	if incoming.Remaining() < 2 {
+		return errTruncated("premium days")
+	}
+	response.PremiumDays = incoming.GetUint16()
+
+	return nil
+}
+
+// splitMotd splits the "id\ntext" MOTD sent by the server, the client shows the text again when the id changes
+func splitMotd(motd string) (uint32, string) {
+	idText, text, found := strings.Cut(motd, "\n")
+	if !found {
+		return 0, motd
+	}
+
+	id, err := strconv.ParseUint(idText, 10, 32)
+	if err != nil {
+		return 0, motd
+	}
+
+	return uint32(id), text
+}
+
+// getString reads a string checking it fits in the packet, since the response comes from the network
+func getString(incoming *packet.Incoming) (string, error) {
+	if incoming.Remaining() < 2 || int(binary.LittleEndian.Uint16(incoming.PeekBuffer())) > incoming.Remaining()-2 {
+		return "", errTruncated("string")
+	}
+
+	return incoming.GetString(), nil
+}
+
+func errTruncated(field string) error {
+	return fmt.Errorf("response is truncated, missing %s", field)
+}
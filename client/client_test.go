@@ -0,0 +1,181 @@
+package client
+
+import (
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/crypt"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/packet"
+	"go-opentibia-loginserver/protocol"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestKey(t *testing.T) *crypt.RSA {
+	privateKey, err := crypt.GenerateRSAKey(1024)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+
+	return crypt.NewRSADecrypterFromKey("key.pem", privateKey)
+}
+
+// serve answers a single login the way the login server does, with the answer built by respond
+func serve(t *testing.T, key *crypt.RSA, respond func(conn net.Conn, request protocol.LoginRequest)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		incoming := packet.NewIncoming(1024)
+		length, err := conn.Read(incoming.PeekBuffer())
+		if err != nil {
+			return
+		}
+		incoming.Resize(length)
+		incoming.GetUint16() // message size
+		incoming.GetUint8()  // opcode
+
+		request, err := protocol.NewLoginParser(key).ParseLogin(incoming)
+		if err != nil {
+			protocol.SendClientError(conn, request.XteaKey, err.Error())
+			return
+		}
+
+		respond(conn, request)
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestBuildLoginPacket(t *testing.T) {
+	key := newTestKey(t)
+
+	data, err := BuildLoginPacket(key.PublicKey(), protocol.LoginRequest{
+		ClientOs:        DefaultClientOs,
+		ProtocolVersion: DefaultProtocolVersion,
+		XteaKey:         [4]uint32{1, 2, 3, 4},
+		AccountNumber:   123456,
+		Password:        "secret",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	incoming := packet.NewIncoming(len(data))
+	copy(incoming.PeekBuffer(), data)
+
+	if size := int(incoming.GetUint16()); size != len(data)-2 {
+		t.Errorf("Expected size header %d, got %d", len(data)-2, size)
+	}
+
+	if opcode := incoming.GetUint8(); opcode != opcodeLogin {
+		t.Errorf("Expected login opcode, got 0x%02X", opcode)
+	}
+
+	request, err := protocol.NewLoginParser(key).ParseLogin(incoming)
+	if err != nil {
+		t.Fatalf("Expected the server to parse the packet, got: %v", err)
+	}
+
+	if request.ProtocolVersion != DefaultProtocolVersion || request.AccountNumber != 123456 || request.Password != "secret" || request.XteaKey != [4]uint32{1, 2, 3, 4} {
+		t.Errorf("Unexpected login request: %+v", request)
+	}
+}
+
+func TestBuildLoginPacketPasswordTooLong(t *testing.T) {
+	key := newTestKey(t)
+
+	password := string(make([]byte, key.BlockSize()))
+	if _, err := BuildLoginPacket(key.PublicKey(), protocol.LoginRequest{Password: password}); err == nil {
+		t.Error("Expected error for a password that does not fit in the RSA block, got none")
+	}
+}
+
+func TestLoginCharacterList(t *testing.T) {
+	key := newTestKey(t)
+	world := &config.World{Name: "Test"}
+
+	address := serve(t, key, func(conn net.Conn, request protocol.LoginRequest) {
+		accountInfo := &models.AccountInfo{Characters: []string{"Knight", "Druid"}, PremiumEndsAt: time.Now().Add(72 * time.Hour).Unix()}
+		protocol.SendClientMotdAndCharacterList(conn, request.XteaKey, models.Motd{Id: 7, Text: "Welcome!"}, accountInfo, world, 0x0100007F, 7172)
+	})
+
+	response, err := Login(Options{Address: address, PublicKey: key.PublicKey(), Timeout: time.Second}, 123456, "secret")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if response.Error != "" || response.MotdId != 7 || response.Motd != "Welcome!" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	if len(response.Characters) != 2 || response.Characters[1].Name != "Druid" || response.Characters[1].World != "Test" {
+		t.Fatalf("Unexpected characters: %+v", response.Characters)
+	}
+
+	if address := response.Characters[0].Address(); address != "127.0.0.1:7172" {
+		t.Errorf("Expected character address 127.0.0.1:7172, got %s", address)
+	}
+
+	if response.PremiumDays < 2 || response.PremiumDays > 3 {
+		t.Errorf("Expected about 3 premium days, got %d", response.PremiumDays)
+	}
+}
+
+func TestLoginError(t *testing.T) {
+	key := newTestKey(t)
+
+	address := serve(t, key, func(conn net.Conn, request protocol.LoginRequest) {
+		protocol.SendClientError(conn, request.XteaKey, "Account number or password is not correct.")
+	})
+
+	response, err := Login(Options{Address: address, PublicKey: key.PublicKey(), Timeout: time.Second}, 123456, "wrong")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if response.Error != "Account number or password is not correct." {
+		t.Errorf("Expected the login error, got %+v", response)
+	}
+}
+
+func TestParseResponseTruncated(t *testing.T) {
+	xteaKey := [4]uint32{1, 2, 3, 4}
+
+	outgoing := packet.NewOutgoing(64)
+	outgoing.AddUint8(opcodeCharacterList)
+	outgoing.AddUint8(1)
+	outgoing.AddString("Knight")
+	outgoing.XteaEncrypt(xteaKey)
+
+	if _, err := ParseResponse(outgoing.Get(), xteaKey); err == nil {
+		t.Error("Expected error for a truncated character list, got none")
+	}
+}
+
+func TestSplitMotd(t *testing.T) {
+	tests := []struct {
+		motd string
+		id   uint32
+		text string
+	}{
+		{"7\nWelcome!", 7, "Welcome!"},
+		{"Welcome!", 0, "Welcome!"},
+		{"news\nWelcome!", 0, "news\nWelcome!"},
+	}
+
+	for _, test := range tests {
+		if id, text := splitMotd(test.motd); id != test.id || text != test.text {
+			t.Errorf("Expected %d %q for %q, got %d %q", test.id, test.text, test.motd, id, text)
+		}
+	}
+}
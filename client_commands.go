@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/rsa"
+	"flag"
+	"fmt"
+	"go-opentibia-loginserver/client"
+	"go-opentibia-loginserver/crypt"
+)
+
+// runLoginTest logs in like a client and prints what the server answered, exiting with 1 when the login failed
+func runLoginTest(args []string) int {
+	flags := flag.NewFlagSet("login-test", flag.ExitOnError)
+	address := flags.String("address", "127.0.0.1:7171", "login server address")
+	account := flags.Uint("account", 0, "account number")
+	password := flags.String("password", "", "account password")
+	passwordFile := flags.String("password-file", "", "file holding the account password")
+	keyFlags := addPublicKeyFlags(flags)
+	protocolVersion := flags.Uint("protocol", uint(client.DefaultProtocolVersion), "protocol version sent by the client")
+	clientOs := flags.Uint("os", uint(client.DefaultClientOs), "operating system sent by the client")
+	timeout := flags.Duration("timeout", client.DefaultTimeout, "connection timeout")
+	flags.Parse(args)
+
+	publicKey, err := keyFlags.load()
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 2
+	}
+
+	accountPassword, err := readPassphrase(*password, *passwordFile)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 2
+	}
+
+	options := client.Options{
+		Address:         *address,
+		PublicKey:       publicKey,
+		ProtocolVersion: uint16(*protocolVersion),
+		ClientOs:        uint16(*clientOs),
+		Timeout:         *timeout,
+	}
+
+	response, err := client.Login(options, uint32(*account), string(accountPassword))
+	if err != nil {
+		fmt.Printf("login failed: %s\n", err)
+		return 1
+	}
+
+	if response.Error != "" {
+		fmt.Printf("login refused: %s\n", response.Error)
+		return 1
+	}
+
+	if response.Motd != "" {
+		fmt.Printf("motd #%d: %s\n", response.MotdId, response.Motd)
+	}
+
+	fmt.Printf("%d characters, %d premium days\n", len(response.Characters), response.PremiumDays)
+	for _, character := range response.Characters {
+		fmt.Printf("  %s (%s) %s\n", character.Name, character.World, character.Address())
+	}
+
+	return 0
+}
+
+// publicKeyFlags select the key the client encrypts the login with, either a private key file or its modulus
+type publicKeyFlags struct {
+	keyFile        *string
+	passphraseFile *string
+	modulus        *string
+}
+
+func addPublicKeyFlags(flags *flag.FlagSet) publicKeyFlags {
+	return publicKeyFlags{
+		keyFile:        flags.String("key", "key.pem", "private key file of the server, its public part is used"),
+		passphraseFile: flags.String("passphrase-file", "", "file holding the passphrase of an encrypted key"),
+		modulus:        flags.String("modulus", "", "decimal modulus the client is patched with, used instead of --key"),
+	}
+}
+
+func (f publicKeyFlags) load() (*rsa.PublicKey, error) {
+	if *f.modulus != "" {
+		return crypt.ParsePublicKeyModulus(*f.modulus)
+	}
+
+	passphrase, err := readPassphrase("", *f.passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := crypt.NewRSADecrypterWithPassphrase(*f.keyFile, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+
+	return key.PublicKey(), nil
+}
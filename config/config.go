@@ -1,21 +1,51 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"go-opentibia-loginserver/utils"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
+const maskedValue = "***"
+
 type World struct {
-	Name     string `yaml:"name"`
-	ID       int    `yaml:"id"`
+	Name      string         `yaml:"name"`
+	ID        int            `yaml:"id"`
+	HostName  string         `yaml:"hostname"`
+	Port      uint16         `yaml:"port"`
+	Addresses []WorldAddress `yaml:"addresses"`
+	Gateways  []WorldGateway `yaml:"gateways"`
+	Balance   string         `yaml:"balance"`
+	HostIP    uint32
+}
+
+// WorldGateway is one of the addresses a world can be reached at, logins are spread between them by the Balance strategy
+type WorldGateway struct {
+	HostName string `yaml:"hostname"`
+	Port     uint16 `yaml:"port"`
+	HostIP   uint32
+}
+
+// WorldAddress advertises a different world address to clients connecting from Network (CIDR notation)
+type WorldAddress struct {
+	Network  string `yaml:"network"`
 	HostName string `yaml:"hostname"`
 	Port     uint16 `yaml:"port"`
 	HostIP   uint32
+	ipNet    *net.IPNet
 }
 
 type LoginServer struct {
@@ -29,12 +59,109 @@ type GameServer struct {
 
 // Config represents the structure of the configuration
 type Config struct {
-	GameServer   GameServer     `yaml:"gameserver"`
-	LoginServer  LoginServer    `yaml:"loginserver"`
-	Database     DatabaseConfig `yaml:"database"`
-	RSAKeyFile   string         `yaml:"rsakeyfile"`
-	Motd         string         `yaml:"motd"`
-	QueryVersion string         `yaml:"queryversion"`
+	GameServer      GameServer     `yaml:"gameserver"`
+	LoginServer     LoginServer    `yaml:"loginserver"`
+	Database        DatabaseConfig `yaml:"database"`
+	RSAKeyFile      string         `yaml:"rsakeyfile"`
+	RSAKeys         []RSAKey       `yaml:"rsakeys"`
+	Motd            string         `yaml:"motd"`
+	MotdSource      string         `yaml:"motdsource"`
+	MotdFile        string         `yaml:"motdfile"`
+	MotdIdFile      string         `yaml:"motdidfile"`
+	MotdRulesFile   string         `yaml:"motdrulesfile"`
+	QueryVersion    string         `yaml:"queryversion"`
+	AccountsFile    string         `yaml:"accountsfile"`
+	PasswordScheme  string         `yaml:"passwordscheme"`
+	PasswordRehash  PasswordRehash `yaml:"passwordrehash"`
+	LoginAudit      LoginAudit     `yaml:"loginaudit"`
+	UpdateLastLogin bool           `yaml:"updatelastlogin"`
+	Metrics         Metrics        `yaml:"metrics"`
+	Log             Log            `yaml:"log"`
+	Admin           Admin          `yaml:"admin"`
+	WatchConfig     bool           `yaml:"watchconfig"`
+	Maintenance     Maintenance    `yaml:"maintenance"`
+	WorldHealth     WorldHealth    `yaml:"worldhealth"`
+	Cast            Cast           `yaml:"cast"`
+	Cam             Cam            `yaml:"cam"`
+}
+
+// Cam turns AccountNumber into the viewer account, which lists the recordings of the cam playback server
+// at HostName and Port instead of characters
+type Cam struct {
+	Enabled       bool   `yaml:"enabled"`
+	AccountNumber uint32 `yaml:"accountnumber"`
+	Source        string `yaml:"source"`
+	Directory     string `yaml:"directory"`
+	WorldName     string `yaml:"worldname"`
+	HostName      string `yaml:"hostname"`
+	Port          uint16 `yaml:"port"`
+	Limit         int    `yaml:"limit"`
+}
+
+// Cast turns AccountNumber into the cast account, which lists the live casts instead of characters
+type Cast struct {
+	Enabled       bool   `yaml:"enabled"`
+	AccountNumber uint32 `yaml:"accountnumber"`
+}
+
+// WorldHealth probes the game servers, OfflineAction decides whether logins to an offline world get an error or no characters
+type WorldHealth struct {
+	Enabled       bool          `yaml:"enabled"`
+	Probe         string        `yaml:"probe"`
+	Interval      time.Duration `yaml:"interval"`
+	Timeout       time.Duration `yaml:"timeout"`
+	OfflineAction string        `yaml:"offlineaction"`
+}
+
+type Maintenance struct {
+	Enabled        bool                `yaml:"enabled"`
+	Message        string              `yaml:"message"`
+	BackAt         string              `yaml:"backat"`
+	MinAccountType uint32              `yaml:"minaccounttype"`
+	Schedule       []MaintenanceWindow `yaml:"schedule"`
+}
+
+// MaintenanceWindow is a daily maintenance period, from and until are local times written as HH:MM
+type MaintenanceWindow struct {
+	From  string `yaml:"from"`
+	Until string `yaml:"until"`
+}
+
+// RSAKey is a private key file, encrypted keys need Passphrase or a PassphraseFile to read it from
+type RSAKey struct {
+	File           string `yaml:"file"`
+	Passphrase     string `yaml:"passphrase"`
+	PassphraseFile string `yaml:"passphrasefile"`
+}
+
+type Admin struct {
+	Enabled  bool   `yaml:"enabled"`
+	HostName string `yaml:"hostname"`
+	Port     int    `yaml:"port"`
+	Token    string `yaml:"token"`
+}
+
+type Log struct {
+	Level          string `yaml:"level"`
+	Format         string `yaml:"format"`
+	RedactAccounts bool   `yaml:"redactaccounts"`
+}
+
+type Metrics struct {
+	Enabled  bool   `yaml:"enabled"`
+	HostName string `yaml:"hostname"`
+	Port     int    `yaml:"port"`
+}
+
+type PasswordRehash struct {
+	Enabled bool   `yaml:"enabled"`
+	Scheme  string `yaml:"scheme"`
+}
+
+type LoginAudit struct {
+	Sink      string `yaml:"sink"`
+	File      string `yaml:"file"`
+	QueueSize int    `yaml:"queuesize"`
 }
 
 type DatabaseConfig struct {
@@ -45,34 +172,196 @@ type DatabaseConfig struct {
 	Port     int    `yaml:"port"`
 }
 
-func LoadConfig() (Config, error) {
-	var config Config
+// DefaultMotdIdFile keeps the MOTD ids when motdidfile is not set, without it a restart would give a changed MOTD
+// an id clients already acknowledged
+const DefaultMotdIdFile = "motd_ids.json"
 
+// SearchPaths are the directories where config.yaml is looked for when no config file is given
+var SearchPaths = []string{".", "/etc/go-opentibia-loginserver"}
+
+var (
+	configFileMutex sync.RWMutex
+	configFileUsed  string
+)
+
+// LoadConfig reads configFile, or config.yaml from SearchPaths when it is empty. Values can be overridden by
+// environment variables (database.password -> DATABASE_PASSWORD), optionally loaded from a .env file, or read
+// from the file named by the variable with a _FILE suffix (DATABASE_PASSWORD_FILE).
+func LoadConfig(configFile string) (Config, error) {
 	err := godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Config{}, fmt.Errorf("error loading .env file: %w", err)
+	}
+
+	v := newViper(configFile)
+	config, err := readConfig(v)
 	if err != nil {
-		log.Fatalf("Error loading .env file")
+		return config, err
+	}
+
+	configFileMutex.Lock()
+	configFileUsed = v.ConfigFileUsed()
+	configFileMutex.Unlock()
+
+	return config, nil
+}
+
+// ReloadConfig reads the config file found by LoadConfig again. Every load uses its own viper instance, so a
+// reload never races with another one.
+func ReloadConfig() (Config, error) {
+	configFileMutex.RLock()
+	configFile := configFileUsed
+	configFileMutex.RUnlock()
+
+	if configFile == "" {
+		return Config{}, errors.New("error reading config file: config was not loaded")
 	}
 
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".") // Set the path to look for the config file in the current directory
+	return readConfig(newViper(configFile))
+}
 
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
+func newViper(configFile string) *viper.Viper {
+	v := viper.New()
 
-	if err := viper.ReadInConfig(); err != nil {
+	if configFile != "" {
+		v.SetConfigFile(configFile)
+	} else {
+		v.SetConfigName("config")
+		v.SetConfigType("yaml")
+		for _, path := range SearchPaths {
+			v.AddConfigPath(path)
+		}
+	}
+
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	v.SetDefault("motdidfile", DefaultMotdIdFile)
+
+	return v
+}
+
+func readConfig(v *viper.Viper) (Config, error) {
+	var config Config
+
+	if err := v.ReadInConfig(); err != nil {
 		return config, fmt.Errorf("error reading config file: %w", err)
 	}
 
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := loadSecretFiles(v); err != nil {
+		return config, err
+	}
+
+	if err := v.Unmarshal(&config); err != nil {
 		return config, fmt.Errorf("unable to decode into struct: %w", err)
 	}
 
 	convertConfigWorldHostnameToIp(&config)
 
+	if err := parseConfigWorldAddresses(&config); err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
 
+func loadSecretFiles(v *viper.Viper) error {
+	for _, key := range v.AllKeys() {
+		envName := strings.ToUpper(strings.ReplaceAll(key, ".", "_")) + "_FILE"
+
+		filename := os.Getenv(envName)
+		if filename == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", envName, err)
+		}
+		v.Set(key, strings.TrimSpace(string(data)))
+	}
+
+	return nil
+}
+
+// WatchConfig calls onChange whenever the config file loaded by LoadConfig is written. The directory is watched
+// rather than the file, so editors that save by replacing the file keep triggering reloads.
+func WatchConfig(onChange func()) error {
+	configFileMutex.RLock()
+	configFile := configFileUsed
+	configFileMutex.RUnlock()
+
+	if configFile == "" {
+		return errors.New("config was not loaded")
+	}
+
+	configFile = filepath.Clean(configFile)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating config watcher: %w", err)
+	}
+
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("error watching %s: %w", configFile, err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if filepath.Clean(event.Name) == configFile && event.Has(fsnotify.Write|fsnotify.Create) {
+					onChange()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("config watcher error", "file", configFile, "error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Masked returns a copy of the config with secrets replaced, safe to be displayed
+func (c Config) Masked() Config {
+	if c.Database.Password != "" {
+		c.Database.Password = maskedValue
+	}
+
+	if c.Admin.Token != "" {
+		c.Admin.Token = maskedValue
+	}
+
+	c.RSAKeys = append([]RSAKey(nil), c.RSAKeys...)
+	for i := range c.RSAKeys {
+		if c.RSAKeys[i].Passphrase != "" {
+			c.RSAKeys[i].Passphrase = maskedValue
+		}
+	}
+
+	return c
+}
+
+// GetRSAKeys returns the keys tried on every login, rsakeyfile first followed by rsakeys
+func (c *Config) GetRSAKeys() []RSAKey {
+	var keys []RSAKey
+
+	if c.RSAKeyFile != "" {
+		keys = append(keys, RSAKey{File: c.RSAKeyFile})
+	}
+
+	return append(keys, c.RSAKeys...)
+}
+
 func GetWorldById(config Config, worldId int) (World, error) {
 	var world World
 
@@ -86,8 +375,60 @@ func GetWorldById(config Config, worldId int) (World, error) {
 	return world, fmt.Errorf("could not find any world with id %d", worldId)
 }
 
-func GetDefaultWorld(config *Config) World {
-	return config.GameServer.Worlds[0]
+// Address returns the gateway host and port, as dialed by the health checks
+func (g *WorldGateway) Address() string {
+	return net.JoinHostPort(g.HostName, strconv.Itoa(int(g.Port)))
+}
+
+// GetGateways returns the configured gateways, or the world host and port when there are none
+func (w *World) GetGateways() []WorldGateway {
+	if len(w.Gateways) > 0 {
+		return w.Gateways
+	}
+
+	return []WorldGateway{{HostName: w.HostName, Port: w.Port, HostIP: w.HostIP}}
+}
+
+// GetNetworkGatewayFor returns the first address rule whose network contains clientIp, as a gateway
+func (w *World) GetNetworkGatewayFor(clientIp uint32) (WorldGateway, bool) {
+	ip := utils.Uint32ToIp(clientIp)
+
+	for _, address := range w.Addresses {
+		if address.ipNet == nil || !address.ipNet.Contains(ip) {
+			continue
+		}
+
+		return address.gateway(w.Port), true
+	}
+
+	return WorldGateway{}, false
+}
+
+// GetNetworkGateways returns every address rule as a gateway, so their addresses can be checked
+func (w *World) GetNetworkGateways() []WorldGateway {
+	gateways := make([]WorldGateway, 0, len(w.Addresses))
+	for _, address := range w.Addresses {
+		gateways = append(gateways, address.gateway(w.Port))
+	}
+
+	return gateways
+}
+
+func (a *WorldAddress) gateway(worldPort uint16) WorldGateway {
+	port := a.Port
+	if port == 0 {
+		port = worldPort
+	}
+
+	return WorldGateway{HostName: a.HostName, Port: port, HostIP: a.HostIP}
+}
+
+func GetDefaultWorld(config *Config) (World, error) {
+	if len(config.GameServer.Worlds) == 0 {
+		return World{}, fmt.Errorf("there is no world configured")
+	}
+
+	return config.GameServer.Worlds[0], nil
 }
 
 func convertConfigWorldHostnameToIp(config *Config) {
@@ -96,7 +437,51 @@ func convertConfigWorldHostnameToIp(config *Config) {
 		if err == nil {
 			config.GameServer.Worlds[i].HostIP = ipAddress
 		} else {
-			fmt.Printf("could not convert world %s host %s to number ip address: %s\n", config.GameServer.Worlds[i].Name, config.GameServer.Worlds[i].HostName, err)
+			slog.Warn("could not convert world host to number ip address", "world", config.GameServer.Worlds[i].Name, "host", config.GameServer.Worlds[i].HostName, "error", err)
 		}
 	}
 }
+
+func parseConfigWorldAddresses(config *Config) error {
+	for i := range config.GameServer.Worlds {
+		if err := config.GameServer.Worlds[i].ParseAddresses(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseAddresses resolves the networks and IPs of the address rules and gateways of the world
+func (w *World) ParseAddresses() error {
+	for j := range w.Addresses {
+		address := &w.Addresses[j]
+
+		_, ipNet, err := net.ParseCIDR(address.Network)
+		if err != nil {
+			return fmt.Errorf("invalid network %s on world %s: %w", address.Network, w.Name, err)
+		}
+		address.ipNet = ipNet
+
+		address.HostIP, err = utils.IpToUint32(address.HostName)
+		if err != nil {
+			return fmt.Errorf("could not convert world %s address %s to number ip address: %w", w.Name, address.HostName, err)
+		}
+	}
+
+	for j := range w.Gateways {
+		gateway := &w.Gateways[j]
+
+		var err error
+		gateway.HostIP, err = utils.IpToUint32(gateway.HostName)
+		if err != nil {
+			return fmt.Errorf("could not convert world %s gateway %s to number ip address: %w", w.Name, gateway.HostName, err)
+		}
+
+		if gateway.Port == 0 {
+			gateway.Port = w.Port
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseConfigWorldAddressesInvalidNetwork(t *testing.T) {
+	config := Config{
+		GameServer: GameServer{
+			Worlds: []World{
+				{Name: "Test", Addresses: []WorldAddress{{Network: "not-a-network", HostName: "10.0.0.1"}}},
+			},
+		},
+	}
+
+	if err := parseConfigWorldAddresses(&config); err == nil {
+		t.Error("expected an error for an invalid network, but got none")
+	}
+}
+
+func TestParseConfigWorldGateways(t *testing.T) {
+	config := Config{
+		GameServer: GameServer{
+			Worlds: []World{
+				{Name: "Test", HostName: "10.0.0.1", Port: 7172, Gateways: []WorldGateway{{HostName: "10.0.1.1"}, {HostName: "10.0.1.2", Port: 7272}}},
+			},
+		},
+	}
+
+	if err := parseConfigWorldAddresses(&config); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	gateways := config.GameServer.Worlds[0].GetGateways()
+	if len(gateways) != 2 || gateways[0].Address() != "10.0.1.1:7172" || gateways[1].Address() != "10.0.1.2:7272" {
+		t.Errorf("expected gateways 10.0.1.1:7172 and 10.0.1.2:7272, got %+v", gateways)
+	}
+
+	config.GameServer.Worlds[0].Gateways = []WorldGateway{{HostName: "gateway.example.com"}}
+	if err := parseConfigWorldAddresses(&config); err == nil {
+		t.Error("expected an error for a gateway hostname that is not an IP address, but got none")
+	}
+}
+
+func TestConfigMasked(t *testing.T) {
+	config := Config{
+		Database: DatabaseConfig{User: "otserv", Password: "secret"},
+		Admin:    Admin{Token: "token"},
+		RSAKeys:  []RSAKey{{File: "custom.pem", Passphrase: "passphrase"}},
+	}
+
+	masked := config.Masked()
+
+	if masked.Database.Password == "secret" || masked.Admin.Token == "token" || masked.RSAKeys[0].Passphrase == "passphrase" {
+		t.Errorf("Expected secrets to be masked, got %+v", masked)
+	}
+
+	if config.RSAKeys[0].Passphrase != "passphrase" {
+		t.Error("Expected the original config to keep its secrets")
+	}
+
+	if masked.Database.User != "otserv" {
+		t.Errorf("Expected database user to be kept, got %s", masked.Database.User)
+	}
+
+	if config.Database.Password != "secret" {
+		t.Error("Expected original config not to be modified")
+	}
+}
+
+func TestLoadConfigSecretFile(t *testing.T) {
+	directory := t.TempDir()
+
+	configFile := filepath.Join(directory, "config.yaml")
+	data := "gameserver:\n  worlds:\n    - name: Test\n      hostname: 127.0.0.1\n      port: 7172\ndatabase:\n  password: placeholder\n"
+	if err := os.WriteFile(configFile, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	secretFile := filepath.Join(directory, "database_password")
+	if err := os.WriteFile(secretFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	t.Setenv("DATABASE_PASSWORD_FILE", secretFile)
+
+	config, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if config.Database.Password != "secret" {
+		t.Errorf("Expected database password to be read from file, got %q", config.Database.Password)
+	}
+
+	if len(config.GameServer.Worlds) != 1 || config.GameServer.Worlds[0].Port != 7172 {
+		t.Errorf("Unexpected worlds: %+v", config.GameServer.Worlds)
+	}
+
+	if config.MotdIdFile != DefaultMotdIdFile {
+		t.Errorf("Expected motdidfile to default to %s, got %q", DefaultMotdIdFile, config.MotdIdFile)
+	}
+}
+
+func TestReloadConfigWhileWatching(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	data := "gameserver:\n  worlds:\n    - name: Test\n      hostname: 127.0.0.1\n      port: 7172\n"
+	if err := os.WriteFile(configFile, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if _, err := LoadConfig(configFile); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	changed := make(chan Config, 10)
+	err := WatchConfig(func() {
+		if config, err := ReloadConfig(); err == nil {
+			changed <- config
+		}
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := os.WriteFile(configFile, []byte(strings.ReplaceAll(data, "7172", "7272")), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	// reloads from other goroutines, like SIGHUP and the admin API, must not race with the watcher
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ReloadConfig()
+		}()
+	}
+	wg.Wait()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case config := <-changed:
+			if len(config.GameServer.Worlds) == 1 && config.GameServer.Worlds[0].Port == 7272 {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Expected the watcher to reload the changed config")
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go-opentibia-loginserver/utils"
+	"os"
+	"strings"
+)
+
+// MinAdminTokenLength is the shortest admin.token accepted, shorter tokens are too easy to guess
+const MinAdminTokenLength = 16
+
+// Validate checks the config for problems that would break logins, reporting all of them at once. Query versions,
+// password schemes, log options and maintenance times are checked by the packages using them.
+func (c *Config) Validate() error {
+	var problems []error
+
+	if len(c.GameServer.Worlds) == 0 {
+		problems = append(problems, fmt.Errorf("gameserver.worlds: at least one world is required"))
+	}
+
+	worldIds := make(map[int]bool)
+	for i, world := range c.GameServer.Worlds {
+		if world.Name == "" {
+			problems = append(problems, fmt.Errorf("gameserver.worlds[%d]: name is required", i))
+		}
+
+		if worldIds[world.ID] {
+			problems = append(problems, fmt.Errorf("gameserver.worlds[%d]: id %d is used by another world", i, world.ID))
+		}
+		worldIds[world.ID] = true
+
+		if world.HostIP == 0 {
+			problems = append(problems, fmt.Errorf("gameserver.worlds[%d]: hostname %q is not a valid IPv4 address", i, world.HostName))
+		}
+
+		if world.Port == 0 {
+			problems = append(problems, fmt.Errorf("gameserver.worlds[%d]: port is required", i))
+		}
+
+		switch world.Balance {
+		case "", "roundrobin", "leastrecent", "iphash":
+		default:
+			problems = append(problems, fmt.Errorf("gameserver.worlds[%d]: unknown balance strategy %q", i, world.Balance))
+		}
+	}
+
+	if !IsValidPort(c.LoginServer.Port) {
+		problems = append(problems, fmt.Errorf("loginserver.port: %d is not a valid port", c.LoginServer.Port))
+	}
+
+	if c.RSAKeyFile == "" && len(c.RSAKeys) == 0 {
+		problems = append(problems, fmt.Errorf("rsakeyfile: is required when there are no rsakeys"))
+	} else if c.RSAKeyFile != "" {
+		if _, err := os.Stat(c.RSAKeyFile); err != nil {
+			problems = append(problems, fmt.Errorf("rsakeyfile: %w", err))
+		}
+	}
+
+	for i, key := range c.RSAKeys {
+		if key.File == "" {
+			problems = append(problems, fmt.Errorf("rsakeys[%d].file: is required", i))
+		} else if _, err := os.Stat(key.File); err != nil {
+			problems = append(problems, fmt.Errorf("rsakeys[%d].file: %w", i, err))
+		}
+
+		if key.PassphraseFile != "" {
+			if _, err := os.Stat(key.PassphraseFile); err != nil {
+				problems = append(problems, fmt.Errorf("rsakeys[%d].passphrasefile: %w", i, err))
+			}
+		}
+	}
+
+	switch c.MotdSource {
+	case "", "config", "database":
+	case "file":
+		if c.MotdFile == "" {
+			problems = append(problems, fmt.Errorf("motdfile: is required by the file MOTD source"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("motdsource: unknown source %q", c.MotdSource))
+	}
+
+	switch c.LoginAudit.Sink {
+	case "", "none", "database":
+	case "file":
+		if c.LoginAudit.File == "" {
+			problems = append(problems, fmt.Errorf("loginaudit.file: is required by the file sink"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("loginaudit.sink: unknown sink %q", c.LoginAudit.Sink))
+	}
+
+	if c.Metrics.Enabled && !IsValidPort(c.Metrics.Port) {
+		problems = append(problems, fmt.Errorf("metrics.port: %d is not a valid port", c.Metrics.Port))
+	}
+
+	if c.Admin.Enabled {
+		// an empty hostname listens on every interface, and the token travels over plain HTTP
+		if c.Admin.HostName == "" {
+			problems = append(problems, fmt.Errorf("admin.hostname: is required, use 127.0.0.1 to keep the admin API local"))
+		}
+
+		if !IsValidPort(c.Admin.Port) {
+			problems = append(problems, fmt.Errorf("admin.port: %d is not a valid port", c.Admin.Port))
+		}
+
+		switch {
+		case c.Admin.Token == "":
+			problems = append(problems, fmt.Errorf("admin.token: is required by the admin API"))
+		case strings.HasPrefix(c.Admin.Token, "${") && strings.HasSuffix(c.Admin.Token, "}"):
+			problems = append(problems, fmt.Errorf("admin.token: %s is not expanded, set the ADMIN_TOKEN or ADMIN_TOKEN_FILE environment variable", c.Admin.Token))
+		case len(c.Admin.Token) < MinAdminTokenLength:
+			problems = append(problems, fmt.Errorf("admin.token: must be at least %d characters long", MinAdminTokenLength))
+		}
+	}
+
+	if c.WorldHealth.Enabled {
+		switch c.WorldHealth.Probe {
+		case "", "tcp", "status":
+		default:
+			problems = append(problems, fmt.Errorf("worldhealth.probe: unknown probe %q", c.WorldHealth.Probe))
+		}
+
+		switch c.WorldHealth.OfflineAction {
+		case "", "error", "hide":
+		default:
+			problems = append(problems, fmt.Errorf("worldhealth.offlineaction: unknown action %q", c.WorldHealth.OfflineAction))
+		}
+
+		if c.WorldHealth.Interval < 0 || c.WorldHealth.Timeout < 0 {
+			problems = append(problems, fmt.Errorf("worldhealth: interval and timeout must not be negative"))
+		}
+	}
+
+	if c.Cam.Enabled {
+		switch c.Cam.Source {
+		case "database":
+		case "directory":
+			if c.Cam.Directory == "" {
+				problems = append(problems, fmt.Errorf("cam.directory: is required by the directory source"))
+			}
+		default:
+			problems = append(problems, fmt.Errorf("cam.source: unknown source %q", c.Cam.Source))
+		}
+
+		if _, err := utils.IpToUint32(c.Cam.HostName); err != nil {
+			problems = append(problems, fmt.Errorf("cam.hostname: %q is not a valid IPv4 address", c.Cam.HostName))
+		}
+
+		if c.Cam.Port == 0 {
+			problems = append(problems, fmt.Errorf("cam.port: is required"))
+		}
+
+		if c.Cast.Enabled && c.Cast.AccountNumber == c.Cam.AccountNumber {
+			problems = append(problems, fmt.Errorf("cam.accountnumber: %d is already the cast account", c.Cam.AccountNumber))
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// IsValidPort reports whether port can be listened on or connected to
+func IsValidPort(port int) bool {
+	return port > 0 && port <= 65535
+}
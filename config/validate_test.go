@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newValidConfig(t *testing.T) Config {
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, []byte("key"), 0644); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	config := Config{
+		GameServer: GameServer{
+			Worlds: []World{
+				{Name: "Test", ID: 0, HostName: "127.0.0.1", Port: 7172},
+				{Name: "Other", ID: 1, HostName: "127.0.0.1", Port: 7173},
+			},
+		},
+		LoginServer:  LoginServer{HostName: "localhost", Port: 7171},
+		Database:     DatabaseConfig{Port: 3306},
+		RSAKeyFile:   keyFile,
+		QueryVersion: "tvp",
+	}
+	convertConfigWorldHostnameToIp(&config)
+
+	return config
+}
+
+func TestValidate(t *testing.T) {
+	config := newValidConfig(t)
+
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	config := newValidConfig(t)
+	config.GameServer.Worlds = append(config.GameServer.Worlds, World{ID: 1, HostName: "not-an-ip", Balance: "random"})
+	convertConfigWorldHostnameToIp(&config)
+	config.LoginServer.Port = 70000
+	config.RSAKeyFile = filepath.Join(t.TempDir(), "missing.pem")
+	config.LoginAudit.Sink = "syslog"
+	config.Admin = Admin{Enabled: true, Port: 9172}
+	config.WorldHealth = WorldHealth{Enabled: true, Probe: "ping", OfflineAction: "ignore"}
+	config.RSAKeys = []RSAKey{{}}
+	config.Cast = Cast{Enabled: true}
+	config.Cam = Cam{Enabled: true, Source: "ftp", HostName: "cams"}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected an error, got none")
+	}
+
+	for _, want := range []string{
+		"name is required",
+		"id 1 is used by another world",
+		"not a valid IPv4 address",
+		"port is required",
+		"unknown balance strategy",
+		"loginserver.port",
+		"rsakeyfile",
+		"rsakeys[0].file",
+		"loginaudit.sink",
+		"admin.hostname",
+		"admin.token",
+		"worldhealth.probe",
+		"worldhealth.offlineaction",
+		"cam.source",
+		"cam.hostname",
+		"cam.port",
+		"already the cast account",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestValidateEmptyWorlds(t *testing.T) {
+	config := newValidConfig(t)
+	config.GameServer.Worlds = nil
+
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an error for a config without worlds, got none")
+	}
+}
+
+func TestValidateAdminToken(t *testing.T) {
+	tests := []struct {
+		token string
+		valid bool
+	}{
+		{"", false},
+		{"${ADMIN_TOKEN}", false},
+		{"short", false},
+		{"0123456789abcdef", true},
+	}
+
+	for _, test := range tests {
+		config := newValidConfig(t)
+		config.Admin = Admin{Enabled: true, HostName: "127.0.0.1", Port: 9172, Token: test.token}
+
+		if err := config.Validate(); (err == nil) != test.valid {
+			t.Errorf("Expected token %q valid %v, got: %v", test.token, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package crypt
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// login blocks start with a zero byte, the xtea key and the account number, followed by the password length
+const (
+	loginBlockPasswordOffset = 21
+	loginBlockHeaderSize     = loginBlockPasswordOffset + 2
+)
+
+// KeyRing decrypts login blocks encrypted with any of its keys, so clients patched with an old and a new key can
+// login while they are migrated. Keys whose size matches the block are tried in order and the first plaintext
+// that looks like a login block wins: a leading zero byte and a password length that fits in the block. A wrong
+// key passes both checks for about one 1024 bit block in 150000, so the key most clients use should still come first.
+type KeyRing struct {
+	keys []*RSA
+
+	// OnDecrypt, when set, is called with the name of the key that decrypted each block
+	OnDecrypt func(keyName string)
+}
+
+func NewKeyRing(keys ...*RSA) *KeyRing {
+	return &KeyRing{keys: keys}
+}
+
+func (k *KeyRing) DecryptNoPadding(ciphertext []byte) ([]byte, error) {
+	var lastErr error
+	triedKeys := 0
+
+	for _, key := range k.keys {
+		if key.BlockSize() != len(ciphertext) {
+			continue
+		}
+		triedKeys++
+
+		plaintext, err := key.DecryptNoPadding(ciphertext)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if isLoginBlock(plaintext) {
+			if k.OnDecrypt != nil {
+				k.OnDecrypt(key.Name())
+			}
+			return plaintext, nil
+		}
+	}
+
+	if triedKeys == 0 {
+		return nil, fmt.Errorf("no key for a block of %d bytes", len(ciphertext))
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("no key decrypted the block: %w", lastErr)
+	}
+
+	return nil, fmt.Errorf("no key decrypted the block")
+}
+
+func isLoginBlock(plaintext []byte) bool {
+	if len(plaintext) < loginBlockHeaderSize || plaintext[0] != 0 {
+		return false
+	}
+
+	passwordLength := int(binary.LittleEndian.Uint16(plaintext[loginBlockPasswordOffset:]))
+	return loginBlockHeaderSize+passwordLength <= len(plaintext)
+}
@@ -0,0 +1,151 @@
+package crypt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"testing"
+)
+
+func newTestKey(t *testing.T, name string) *RSA {
+	return newTestKeyWithSize(t, name, 1024)
+}
+
+func newTestKeyWithSize(t *testing.T, name string, bits int) *RSA {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+
+	return NewRSADecrypterFromKey(name, privateKey)
+}
+
+// encryptLoginBlock encrypts a login block with key, varying its last byte until none of the other keys
+// happens to decrypt it to a login block as well
+func encryptLoginBlock(t *testing.T, key *RSA, otherKeys ...*RSA) []byte {
+	plaintext := make([]byte, key.BlockSize())
+	copy(plaintext[1:], "login block")
+
+	for attempt := 0; attempt < 256; attempt++ {
+		plaintext[len(plaintext)-1] = byte(attempt)
+
+		ciphertext, err := EncryptNoPadding(key.PublicKey(), plaintext)
+		if err != nil {
+			t.Fatalf("Failed to encrypt login block: %v", err)
+		}
+
+		ambiguous := false
+		for _, otherKey := range otherKeys {
+			if otherKey == key {
+				continue
+			}
+
+			decrypted, err := otherKey.DecryptNoPadding(ciphertext)
+			ambiguous = ambiguous || (err == nil && isLoginBlock(decrypted))
+		}
+
+		if !ambiguous {
+			return ciphertext
+		}
+	}
+
+	t.Fatal("Failed to encrypt an unambiguous login block")
+	return nil
+}
+
+func TestKeyRingPicksMatchingKey(t *testing.T) {
+	standardKey := newTestKey(t, "standard.pem")
+	customKey := newTestKey(t, "custom.pem")
+
+	var used []string
+	keyRing := NewKeyRing(customKey, standardKey)
+	keyRing.OnDecrypt = func(keyName string) {
+		used = append(used, keyName)
+	}
+
+	for _, key := range []*RSA{standardKey, customKey} {
+		plaintext, err := keyRing.DecryptNoPadding(encryptLoginBlock(t, key, standardKey, customKey))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if string(plaintext[1:12]) != "login block" {
+			t.Errorf("Expected the login block to be decrypted by %s, got %x", key.Name(), plaintext)
+		}
+	}
+
+	if len(used) != 2 || used[0] != "standard.pem" || used[1] != "custom.pem" {
+		t.Errorf("Expected standard.pem then custom.pem to be reported, got %v", used)
+	}
+}
+
+func TestKeyRingWithoutMatchingKey(t *testing.T) {
+	keyRing := NewKeyRing(newTestKey(t, "custom.pem"))
+
+	if _, err := keyRing.DecryptNoPadding(make([]byte, 64)); err == nil {
+		t.Error("Expected error for a block no key can decrypt, got none")
+	}
+}
+
+func TestKeyRingWithDifferentKeySizes(t *testing.T) {
+	standardKey := newTestKey(t, "standard.pem")
+	largeKey := newTestKeyWithSize(t, "large.pem", 2048)
+	keyRing := NewKeyRing(standardKey, largeKey)
+
+	for _, key := range []*RSA{standardKey, largeKey} {
+		plaintext, err := keyRing.DecryptNoPadding(encryptLoginBlock(t, key))
+		if err != nil {
+			t.Fatalf("Expected no error for a block of %s, got: %v", key.Name(), err)
+		}
+
+		if len(plaintext) != key.BlockSize() || string(plaintext[1:12]) != "login block" {
+			t.Errorf("Expected the login block to be decrypted by %s, got %x", key.Name(), plaintext)
+		}
+	}
+
+	if _, err := keyRing.DecryptNoPadding(make([]byte, 512)); err == nil {
+		t.Error("Expected error for a block no key has the size of, got none")
+	}
+}
+
+func TestKeyRingSkipsLeadingZeroFromWrongKey(t *testing.T) {
+	firstKey := newTestKey(t, "first.pem")
+	secondKey := newTestKey(t, "second.pem")
+
+	plaintext := make([]byte, secondKey.BlockSize())
+	copy(plaintext[1:], "login block")
+
+	// find a block of the second key that the first key decrypts to a leading zero with a password length
+	// that does not fit, which the first key used to be picked for
+	var ciphertext []byte
+	for attempt := 0; ciphertext == nil; attempt++ {
+		if attempt == 100000 {
+			t.Fatal("Failed to find a block the first key decrypts to a leading zero")
+		}
+		binary.BigEndian.PutUint32(plaintext[len(plaintext)-4:], uint32(attempt))
+
+		encrypted, err := EncryptNoPadding(secondKey.PublicKey(), plaintext)
+		if err != nil {
+			t.Fatalf("Failed to encrypt login block: %v", err)
+		}
+
+		if decrypted, err := firstKey.DecryptNoPadding(encrypted); err == nil && decrypted[0] == 0 && !isLoginBlock(decrypted) {
+			ciphertext = encrypted
+		}
+	}
+
+	var used string
+	keyRing := NewKeyRing(firstKey, secondKey)
+	keyRing.OnDecrypt = func(keyName string) {
+		used = keyName
+	}
+
+	decrypted, err := keyRing.DecryptNoPadding(ciphertext)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if used != "second.pem" || string(decrypted[1:12]) != "login block" {
+		t.Errorf("Expected the block to be decrypted by second.pem, got %s %x", used, decrypted)
+	}
+}
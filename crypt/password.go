@@ -0,0 +1,238 @@
+package crypt
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordSchemeAuto   = "auto"
+	PasswordSchemePlain  = "plain"
+	PasswordSchemeMD5    = "md5"
+	PasswordSchemeSHA1   = "sha1"
+	PasswordSchemeSHA256 = "sha256"
+	PasswordSchemeBcrypt = "bcrypt"
+	PasswordSchemeArgon2 = "argon2"
+
+	// PasswordSchemeUnknown is detected for hashes of no known scheme, no password matches them
+	PasswordSchemeUnknown = "unknown"
+)
+
+// PasswordVerifier checks a client provided password against the hash (and optional salt) stored for the account
+type PasswordVerifier interface {
+	Verify(password string, salt string, hash string) bool
+}
+
+// PasswordHasher creates new hashes, used to migrate accounts to a modern scheme
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+}
+
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024
+	argon2Threads = 4
+	argon2SaltLen = 16
+	argon2KeyLen  = 32
+
+	// argon2MaxConcurrent limits the argon2 keys derived at once, each one allocates the memory cost of its hash
+	// (64 MiB for the hashes created here), so wrong passwords can not make the server allocate gigabytes
+	argon2MaxConcurrent = 4
+)
+
+var argon2Slots = make(chan struct{}, argon2MaxConcurrent)
+
+// deriveArgon2Key computes an argon2 key once one of the argon2Slots is free
+func deriveArgon2Key(variant string, password []byte, salt []byte, time uint32, memory uint32, threads uint8, keyLen uint32) []byte {
+	argon2Slots <- struct{}{}
+	defer func() { <-argon2Slots }()
+
+	if variant == "argon2i" {
+		return argon2.Key(password, salt, time, memory, threads, keyLen)
+	}
+	return argon2.IDKey(password, salt, time, memory, threads, keyLen)
+}
+
+// GetPasswordVerifier returns the verifier for a scheme, or nil when the scheme is unknown
+func GetPasswordVerifier(scheme string) PasswordVerifier {
+	switch scheme {
+	case PasswordSchemeAuto:
+		return &autoVerifier{}
+	case PasswordSchemePlain:
+		return &plainVerifier{}
+	case PasswordSchemeMD5:
+		return &digestVerifier{newHash: md5.New}
+	case PasswordSchemeSHA1:
+		return &digestVerifier{newHash: sha1.New}
+	case PasswordSchemeSHA256:
+		return &digestVerifier{newHash: sha256.New}
+	case PasswordSchemeBcrypt:
+		return &bcryptVerifier{}
+	case PasswordSchemeArgon2:
+		return &argon2Verifier{}
+	}
+
+	return nil
+}
+
+// GetPasswordHasher returns the hasher for a scheme, or nil when new hashes should not be created with it
+func GetPasswordHasher(scheme string) PasswordHasher {
+	switch scheme {
+	case PasswordSchemeBcrypt:
+		return &bcryptVerifier{}
+	case PasswordSchemeArgon2:
+		return &argon2Verifier{}
+	}
+
+	return nil
+}
+
+// DetectPasswordScheme guesses the scheme of a stored hash from its prefix or, for hex digests, its length.
+// Anything that is not recognized is PasswordSchemeUnknown, plain text is only used when configured explicitly.
+func DetectPasswordScheme(hash string) string {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return PasswordSchemeBcrypt
+	case strings.HasPrefix(hash, "$argon2"):
+		return PasswordSchemeArgon2
+	}
+
+	if _, err := hex.DecodeString(hash); err == nil {
+		switch len(hash) {
+		case md5.Size * 2:
+			return PasswordSchemeMD5
+		case sha1.Size * 2:
+			return PasswordSchemeSHA1
+		case sha256.Size * 2:
+			return PasswordSchemeSHA256
+		}
+	}
+
+	return PasswordSchemeUnknown
+}
+
+type autoVerifier struct{}
+
+func (v *autoVerifier) Verify(password string, salt string, hash string) bool {
+	scheme := DetectPasswordScheme(hash)
+	if scheme == PasswordSchemeUnknown {
+		return false
+	}
+
+	return GetPasswordVerifier(scheme).Verify(password, salt, hash)
+}
+
+type plainVerifier struct{}
+
+func (v *plainVerifier) Verify(password string, salt string, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(password), []byte(hash)) == 1
+}
+
+// digestVerifier handles hex encoded digests of salt + password, as stored by TFS (the salt is empty on unsalted schemas)
+type digestVerifier struct {
+	newHash func() hash.Hash
+}
+
+func (v *digestVerifier) Verify(password string, salt string, hash string) bool {
+	hasher := v.newHash()
+	hasher.Write([]byte(salt + password))
+	digest := hex.EncodeToString(hasher.Sum(nil))
+
+	return subtle.ConstantTimeCompare([]byte(digest), []byte(strings.ToLower(hash))) == 1
+}
+
+type bcryptVerifier struct{}
+
+func (v *bcryptVerifier) Verify(password string, salt string, hash string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (v *bcryptVerifier) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate bcrypt hash: %w", err)
+	}
+	return string(hash), nil
+}
+
+type argon2Verifier struct{}
+
+// Hash creates an argon2id hash in the PHC string format
+func (v *argon2Verifier) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate argon2 salt: %w", err)
+	}
+
+	key := deriveArgon2Key("argon2id", []byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify expects the PHC string format, e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+func (v *argon2Verifier) Verify(password string, salt string, hash string) bool {
+	params, err := parseArgon2Hash(hash)
+	if err != nil {
+		return false
+	}
+
+	derived := deriveArgon2Key(params.variant, []byte(password), params.salt, params.time, params.memory, params.threads, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(derived, params.key) == 1
+}
+
+type argon2Params struct {
+	variant string
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func parseArgon2Hash(hash string) (argon2Params, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, fmt.Errorf("invalid argon2 hash format")
+	}
+
+	params.variant = parts[1]
+	if params.variant != "argon2id" && params.variant != "argon2i" {
+		return params, fmt.Errorf("unsupported argon2 variant: %s", params.variant)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, fmt.Errorf("unsupported argon2 version: %s", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+
+	var err error
+	params.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+
+	params.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(params.key) == 0 {
+		return params, fmt.Errorf("invalid argon2 key")
+	}
+
+	return params, nil
+}
@@ -0,0 +1,154 @@
+package crypt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordVerifiers(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("hello"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to generate bcrypt hash: %v", err)
+	}
+
+	argon2Salt := []byte("somesaltvalue")
+	argon2Key := argon2.IDKey([]byte("hello"), argon2Salt, 1, 64, 1, 32)
+	argon2Hash := fmt.Sprintf("$argon2id$v=%d$m=64,t=1,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(argon2Salt), base64.RawStdEncoding.EncodeToString(argon2Key))
+
+	tests := []struct {
+		scheme string
+		salt   string
+		hash   string
+	}{
+		{PasswordSchemePlain, "", "hello"},
+		{PasswordSchemeMD5, "", "5d41402abc4b2a76b9719d911017c592"},
+		{PasswordSchemeSHA1, "", "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{PasswordSchemeSHA1, "", "AAF4C61DDCC5E8A2DABEDE0F3B482CD9AEA9434D"},
+		{PasswordSchemeSHA1, "abc", "784d4a296292ecbdea01bee04a5dd2119fc203ef"}, // sha1("abc" + "hello")
+		{PasswordSchemeSHA256, "", "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{PasswordSchemeBcrypt, "", string(bcryptHash)},
+		{PasswordSchemeArgon2, "", argon2Hash},
+	}
+
+	for _, test := range tests {
+		schemes := []string{test.scheme, PasswordSchemeAuto}
+		if test.scheme == PasswordSchemePlain {
+			schemes = schemes[:1] // auto never treats a hash as plain text
+		}
+
+		for _, scheme := range schemes {
+			verifier := GetPasswordVerifier(scheme)
+
+			if !verifier.Verify("hello", test.salt, test.hash) {
+				t.Errorf("%s: expected password to match hash %s", scheme, test.hash)
+			}
+
+			if verifier.Verify("wrong", test.salt, test.hash) {
+				t.Errorf("%s: expected wrong password not to match hash %s", scheme, test.hash)
+			}
+		}
+	}
+}
+
+func TestDetectPasswordScheme(t *testing.T) {
+	tests := []struct {
+		hash     string
+		expected string
+	}{
+		{"5d41402abc4b2a76b9719d911017c592", PasswordSchemeMD5},
+		{"aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", PasswordSchemeSHA1},
+		{"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", PasswordSchemeSHA256},
+		{"$2y$10$abcdefghijklmnopqrstuu", PasswordSchemeBcrypt},
+		{"$argon2id$v=19$m=64,t=1,p=1$c2FsdA$a2V5", PasswordSchemeArgon2},
+		{"not a hash", PasswordSchemeUnknown},
+		{"zzf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", PasswordSchemeUnknown},
+	}
+
+	for _, test := range tests {
+		result := DetectPasswordScheme(test.hash)
+		if result != test.expected {
+			t.Errorf("DetectPasswordScheme(%s) = %s; expected %s", test.hash, result, test.expected)
+		}
+	}
+}
+
+func TestAutoVerifier_UnknownScheme(t *testing.T) {
+	verifier := GetPasswordVerifier(PasswordSchemeAuto)
+
+	if verifier.Verify("hello", "", "hello") {
+		t.Error("Expected a plain text password not to match with the auto scheme")
+	}
+}
+
+func TestGetPasswordVerifier_UnknownScheme(t *testing.T) {
+	if GetPasswordVerifier("rot13") != nil {
+		t.Error("Expected nil verifier for unknown scheme")
+	}
+}
+
+func TestArgon2Verifier_InvalidHash(t *testing.T) {
+	verifier := GetPasswordVerifier(PasswordSchemeArgon2)
+
+	if verifier.Verify("hello", "", "$argon2id$v=19$garbage") {
+		t.Error("Expected malformed argon2 hash not to match")
+	}
+}
+
+func TestPasswordHashers(t *testing.T) {
+	for _, scheme := range []string{PasswordSchemeBcrypt, PasswordSchemeArgon2} {
+		hash, err := GetPasswordHasher(scheme).Hash("hello")
+		if err != nil {
+			t.Fatalf("%s: expected no error, got: %v", scheme, err)
+		}
+
+		if DetectPasswordScheme(hash) != scheme {
+			t.Errorf("%s: expected hash %s to be detected as %s", scheme, hash, scheme)
+		}
+
+		if !GetPasswordVerifier(scheme).Verify("hello", "", hash) {
+			t.Errorf("%s: expected password to match the generated hash", scheme)
+		}
+	}
+}
+
+func TestGetPasswordHasher_LegacyScheme(t *testing.T) {
+	if GetPasswordHasher(PasswordSchemeSHA1) != nil {
+		t.Error("Expected nil hasher for a legacy scheme")
+	}
+}
+
+func TestArgon2VerifierWaitsForSlot(t *testing.T) {
+	hash, err := GetPasswordHasher(PasswordSchemeArgon2).Hash("hello")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for i := 0; i < argon2MaxConcurrent; i++ {
+		argon2Slots <- struct{}{}
+	}
+
+	verified := make(chan bool)
+	go func() {
+		verified <- GetPasswordVerifier(PasswordSchemeArgon2).Verify("hello", "", hash)
+	}()
+
+	select {
+	case <-verified:
+		t.Fatal("Expected the verification to wait while every argon2 slot is taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for i := 0; i < argon2MaxConcurrent; i++ {
+		<-argon2Slots
+	}
+
+	if !<-verified {
+		t.Error("Expected password to match once a slot is free")
+	}
+}
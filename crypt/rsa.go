@@ -1,71 +1,268 @@
 package crypt
 
 import (
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"math/big"
 	"os"
+	"path/filepath"
 )
 
+// MinRSAKeyBits is the smallest key accepted by GenerateRSAKey, the size used by the original clients
+const MinRSAKeyBits = 1024
+
+// ClientPublicExponent is the public exponent built into the clients, only the modulus can be patched
+const ClientPublicExponent = 65537
+
 type Decrypter interface {
 	DecryptNoPadding(ciphertext []byte) ([]byte, error)
 }
 
 type RSA struct {
+	name       string
 	privateKey *rsa.PrivateKey
 }
 
 func NewRSADecrypter(pemFile string) (*RSA, error) {
-	r := &RSA{}
-	err := r.LoadPEM(pemFile)
+	return NewRSADecrypterWithPassphrase(pemFile, nil)
+}
+
+// NewRSADecrypterWithPassphrase loads a key that may be encrypted with passphrase
+func NewRSADecrypterWithPassphrase(pemFile string, passphrase []byte) (*RSA, error) {
+	r := &RSA{name: filepath.Base(pemFile)}
+	err := r.LoadPEMWithPassphrase(pemFile, passphrase)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load RSA private key from %s: %w", pemFile, err)
 	}
 	return r, nil
 }
 
+// NewRSADecrypterFromKey uses a key already in memory, name identifies it like the base name of a key file
+func NewRSADecrypterFromKey(name string, privateKey *rsa.PrivateKey) *RSA {
+	privateKey.Precompute()
+	return &RSA{name: name, privateKey: privateKey}
+}
+
 func (r *RSA) LoadPEM(filename string) error {
+	return r.LoadPEMWithPassphrase(filename, nil)
+}
+
+// LoadPEMWithPassphrase reads a PKCS#1 ("RSA PRIVATE KEY") or PKCS#8 ("PRIVATE KEY") key. Encrypted keys,
+// either PKCS#8 ("ENCRYPTED PRIVATE KEY") or PKCS#1 with the legacy PEM encryption headers, need passphrase.
+func (r *RSA) LoadPEMWithPassphrase(filename string, passphrase []byte) error {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %v", filename, err)
 	}
 
 	block, _ := pem.Decode(data)
-	if block == nil || block.Type != "RSA PRIVATE KEY" {
+	if block == nil {
 		return fmt.Errorf("failed to decode PEM block containing private key")
 	}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	privateKey, err := parsePrivateKeyBlock(block, passphrase)
 	if err != nil {
-		return fmt.Errorf("failed to parse RSA private key: %v", err)
+		return err
 	}
+	privateKey.Precompute()
 
 	r.privateKey = privateKey
 	return nil
 }
 
+func parsePrivateKeyBlock(block *pem.Block, passphrase []byte) (*rsa.PrivateKey, error) {
+	der := block.Bytes
+	var err error
+
+	// legacy PEM encryption is deprecated, but still written by "openssl rsa -des3" and old guides
+	if x509.IsEncryptedPEMBlock(block) {
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("private key is encrypted, a passphrase is required")
+		}
+
+		der, err = x509.DecryptPEMBlock(block, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %v", err)
+		}
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RSA private key: %v", err)
+		}
+		return privateKey, nil
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, fmt.Errorf("private key is encrypted, a passphrase is required")
+		}
+
+		der, err = decryptPKCS8(der, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %v", err)
+		}
+		return parsePKCS8RSAPrivateKey(der)
+	case "PRIVATE KEY":
+		return parsePKCS8RSAPrivateKey(der)
+	}
+
+	return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+}
+
+func parsePKCS8RSAPrivateKey(der []byte) (*rsa.PrivateKey, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PKCS#8 private key: %v", err)
+	}
+
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is a %T, not an RSA key", key)
+	}
+
+	return privateKey, nil
+}
+
+// Name identifies the key in logs and metrics, it is the base name of the key file
+func (r *RSA) Name() string {
+	return r.name
+}
+
+// PublicKey returns the public part of the loaded key, the modulus is what clients are patched with
+func (r *RSA) PublicKey() *rsa.PublicKey {
+	return &r.privateKey.PublicKey
+}
+
+// GenerateRSAKey creates a new key for the login protocol
+func GenerateRSAKey(bits int) (*rsa.PrivateKey, error) {
+	if bits < MinRSAKeyBits {
+		return nil, fmt.Errorf("key size must be at least %d bits, got %d", MinRSAKeyBits, bits)
+	}
+
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+// EncodeRSAPrivateKeyPEM encodes a key in the PKCS#1 PEM format read by LoadPEM
+func EncodeRSAPrivateKeyPEM(privateKey *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+}
+
+// ParsePublicKeyModulus builds the public key clients are patched with from its decimal modulus,
+// clients always use ClientPublicExponent
+func ParsePublicKeyModulus(modulus string) (*rsa.PublicKey, error) {
+	n, ok := new(big.Int).SetString(modulus, 10)
+	if !ok || n.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid modulus, expected a decimal number")
+	}
+
+	if n.BitLen() < MinRSAKeyBits {
+		return nil, fmt.Errorf("modulus must be at least %d bits, got %d", MinRSAKeyBits, n.BitLen())
+	}
+
+	return &rsa.PublicKey{N: n, E: ClientPublicExponent}, nil
+}
+
+// EncryptNoPadding performs the raw RSA encryption done by clients, plaintext must be as long as the modulus
+func EncryptNoPadding(publicKey *rsa.PublicKey, plaintext []byte) ([]byte, error) {
+	size := publicKey.Size()
+	if len(plaintext) != size {
+		return nil, fmt.Errorf("invalid plaintext length: %d, expected %d", len(plaintext), size)
+	}
+
+	m := new(big.Int).SetBytes(plaintext)
+	if m.Cmp(publicKey.N) >= 0 {
+		return nil, fmt.Errorf("plaintext is too large for the key modulus")
+	}
+
+	c := new(big.Int).Exp(m, big.NewInt(int64(publicKey.E)), publicKey.N)
+	return c.FillBytes(make([]byte, size)), nil
+}
+
+// BlockSize is the length of the blocks encrypted with the key, the size of its modulus in bytes
+func (r *RSA) BlockSize() int {
+	return r.privateKey.Size()
+}
+
 func (r *RSA) DecryptNoPadding(ciphertext []byte) ([]byte, error) {
-	if len(ciphertext) != 128 { // Ensure it's 128 bytes for a 1024-bit RSA key
-		return nil, fmt.Errorf("invalid ciphertext length: %d", len(ciphertext))
+	blockSize := r.BlockSize()
+	if len(ciphertext) != blockSize { // a block is exactly as long as the key modulus, 128 bytes for a 1024-bit key
+		return nil, fmt.Errorf("invalid ciphertext length: %d, expected %d", len(ciphertext), blockSize)
 	}
 
 	// Convert ciphertext to big.Int
 	c := new(big.Int).SetBytes(ciphertext)
 
 	// Perform the raw RSA decryption: m = c^d mod n
-	m := new(big.Int).Exp(c, r.privateKey.D, r.privateKey.N)
+	m, err := r.decrypt(c)
+	if err != nil {
+		return nil, err
+	}
 
 	// Extract the plaintext bytes
 	plaintext := m.Bytes()
 
-	// Since m.Bytes() might return fewer than 128 bytes if the plaintext has leading zeros,
+	// Since m.Bytes() might return fewer than blockSize bytes if the plaintext has leading zeros,
 	// pad it manually if needed to get the exact size.
-	if len(plaintext) < 128 {
-		padding := make([]byte, 128-len(plaintext))
+	if len(plaintext) < blockSize {
+		padding := make([]byte, blockSize-len(plaintext))
 		plaintext = append(padding, plaintext...)
 	}
 
 	return plaintext, nil
 }
+
+// decrypt computes c^d mod n with the CRT values of the key, two to three times faster than a plain
+// exponentiation. The ciphertext is blinded by a random factor first, so the time taken does not depend on it.
+func (r *RSA) decrypt(c *big.Int) (*big.Int, error) {
+	key := r.privateKey
+	if len(key.Primes) != 2 || key.Precomputed.Dp == nil {
+		return new(big.Int).Exp(c, key.D, key.N), nil
+	}
+
+	blinding, unblinding, err := blindingFactors(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	blinded := new(big.Int).Mul(c, blinding)
+	blinded.Mod(blinded, key.N)
+
+	// m1 = c^dP mod p, m2 = c^dQ mod q, m = m2 + q * (qInv * (m1 - m2) mod p)
+	p, q := key.Primes[0], key.Primes[1]
+	m1 := new(big.Int).Exp(blinded, key.Precomputed.Dp, p)
+	m2 := new(big.Int).Exp(blinded, key.Precomputed.Dq, q)
+
+	m := m1.Sub(m1, m2)
+	m.Mul(m, key.Precomputed.Qinv)
+	m.Mod(m, p)
+	m.Mul(m, q)
+	m.Add(m, m2)
+
+	m.Mul(m, unblinding)
+	return m.Mod(m, key.N), nil
+}
+
+// blindingFactors returns r^e and r^-1 mod n for a random r, decrypting c * r^e gives m * r
+func blindingFactors(publicKey *rsa.PublicKey) (*big.Int, *big.Int, error) {
+	for {
+		random, err := rand.Int(rand.Reader, publicKey.N)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate blinding factor: %w", err)
+		}
+
+		unblinding := new(big.Int).ModInverse(random, publicKey.N)
+		if random.Sign() == 0 || unblinding == nil {
+			continue
+		}
+
+		blinding := new(big.Int).Exp(random, big.NewInt(int64(publicKey.E)), publicKey.N)
+		return blinding, unblinding, nil
+	}
+}
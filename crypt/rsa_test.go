@@ -1,11 +1,14 @@
 package crypt
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"math/big"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -100,3 +103,258 @@ func TestDecryptNoPadding_ValidCiphertext(t *testing.T) {
 		t.Errorf("Expected plaintext length to be 128, got: %d", len(plaintext))
 	}
 }
+
+func TestGenerateRSAKey(t *testing.T) {
+	if _, err := GenerateRSAKey(512); err == nil {
+		t.Error("Expected error for a key smaller than 1024 bits, got none")
+	}
+
+	privateKey, err := GenerateRSAKey(1024)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	tempPEMFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(tempPEMFile, EncodeRSAPrivateKeyPEM(privateKey), 0600); err != nil {
+		t.Fatalf("Failed to write test PEM file: %v", err)
+	}
+
+	rsaObj, err := NewRSADecrypter(tempPEMFile)
+	if err != nil {
+		t.Fatalf("Expected generated key to be loaded, got: %v", err)
+	}
+
+	if rsaObj.PublicKey().N.Cmp(privateKey.N) != 0 {
+		t.Error("Expected loaded modulus to match the generated key")
+	}
+}
+
+func TestEncryptNoPaddingRoundTrip(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+	rsaObj := &RSA{privateKey: privateKey}
+
+	plaintext := make([]byte, 128)
+	copy(plaintext[1:], "login packet")
+
+	ciphertext, err := EncryptNoPadding(rsaObj.PublicKey(), plaintext)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	decrypted, err := rsaObj.DecryptNoPadding(ciphertext)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected %x, got %x", plaintext, decrypted)
+	}
+
+	if _, err := EncryptNoPadding(rsaObj.PublicKey(), plaintext[:64]); err == nil {
+		t.Error("Expected error for a plaintext shorter than the modulus, got none")
+	}
+}
+
+func TestParsePublicKeyModulus(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+
+	publicKey, err := ParsePublicKeyModulus(privateKey.N.String())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if publicKey.N.Cmp(privateKey.N) != 0 || publicKey.E != privateKey.E {
+		t.Errorf("Expected the public key of the generated key, got %+v", publicKey)
+	}
+
+	for _, modulus := range []string{"", "0x1234", "-5", "65537"} {
+		if _, err := ParsePublicKeyModulus(modulus); err == nil {
+			t.Errorf("Expected error for modulus %q, got none", modulus)
+		}
+	}
+}
+
+func writePEMFile(t *testing.T, block *pem.Block) string {
+	filename := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(filename, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Failed to write test PEM file: %v", err)
+	}
+	return filename
+}
+
+func TestLoadPEM_PKCS8(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal PKCS#8 key: %v", err)
+	}
+
+	rsaObj, err := NewRSADecrypter(writePEMFile(t, &pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Expected no error when loading PKCS#8 key, got: %v", err)
+	}
+
+	if rsaObj.privateKey.N.Cmp(privateKey.N) != 0 {
+		t.Error("Expected loaded modulus to match the PKCS#8 key")
+	}
+}
+
+func TestLoadPEM_EncryptedPKCS8(t *testing.T) {
+	// generated by "openssl pkcs8 -topk8 -v2 <cipher> -v2prf <hash>" from the same key, passphrase "opentibia"
+	files := []string{"testdata/pkcs8_aes256_sha256.pem", "testdata/pkcs8_aes128_sha1.pem"}
+
+	var modulus *big.Int
+	for _, file := range files {
+		rsaObj, err := NewRSADecrypterWithPassphrase(file, []byte("opentibia"))
+		if err != nil {
+			t.Fatalf("Expected no error when loading %s, got: %v", file, err)
+		}
+
+		if modulus != nil && rsaObj.privateKey.N.Cmp(modulus) != 0 {
+			t.Errorf("Expected %s to hold the same key as %s", file, files[0])
+		}
+		modulus = rsaObj.privateKey.N
+	}
+
+	if _, err := NewRSADecrypterWithPassphrase(files[0], []byte("wrong")); err == nil {
+		t.Error("Expected error for a wrong passphrase, got none")
+	}
+
+	if _, err := NewRSADecrypter(files[0]); err == nil {
+		t.Error("Expected error for an encrypted key without passphrase, got none")
+	}
+}
+
+func TestLoadPEM_LegacyEncryptedPEM(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(privateKey), []byte("opentibia"), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatalf("Failed to encrypt PEM block: %v", err)
+	}
+	filename := writePEMFile(t, block)
+
+	rsaObj, err := NewRSADecrypterWithPassphrase(filename, []byte("opentibia"))
+	if err != nil {
+		t.Fatalf("Expected no error when loading encrypted key, got: %v", err)
+	}
+
+	if rsaObj.privateKey.N.Cmp(privateKey.N) != 0 {
+		t.Error("Expected loaded modulus to match the encrypted key")
+	}
+
+	if _, err := NewRSADecrypter(filename); err == nil {
+		t.Error("Expected error for an encrypted key without passphrase, got none")
+	}
+}
+
+func TestDecryptNoPadding_KeySizes(t *testing.T) {
+	for _, bits := range []int{1024, 2048, 4096} {
+		privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			t.Fatalf("Failed to generate %d-bit test private key: %v", bits, err)
+		}
+		rsaObj := &RSA{privateKey: privateKey}
+
+		if rsaObj.BlockSize() != bits/8 {
+			t.Errorf("Expected block size %d for a %d-bit key, got %d", bits/8, bits, rsaObj.BlockSize())
+		}
+
+		plaintext := make([]byte, bits/8)
+		copy(plaintext[1:], "login packet")
+
+		ciphertext, err := EncryptNoPadding(rsaObj.PublicKey(), plaintext)
+		if err != nil {
+			t.Fatalf("Expected no error encrypting with a %d-bit key, got: %v", bits, err)
+		}
+
+		decrypted, err := rsaObj.DecryptNoPadding(ciphertext)
+		if err != nil {
+			t.Fatalf("Expected no error decrypting with a %d-bit key, got: %v", bits, err)
+		}
+
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Expected %d-bit round trip to match, got %x", bits, decrypted)
+		}
+
+		if _, err := rsaObj.DecryptNoPadding(ciphertext[1:]); err == nil {
+			t.Errorf("Expected error for a block shorter than the %d-bit key, got none", bits)
+		}
+	}
+}
+
+// decryptNoPaddingPlain is the plain c^d mod n decryption, the reference for the CRT implementation
+func decryptNoPaddingPlain(privateKey *rsa.PrivateKey, ciphertext []byte) []byte {
+	c := new(big.Int).SetBytes(ciphertext)
+	m := new(big.Int).Exp(c, privateKey.D, privateKey.N)
+	return m.FillBytes(make([]byte, privateKey.Size()))
+}
+
+func TestDecryptNoPadding_MatchesPlainDecryption(t *testing.T) {
+	for _, bits := range []int{1024, 2048} {
+		privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			t.Fatalf("Failed to generate %d-bit test private key: %v", bits, err)
+		}
+		rsaObj := &RSA{privateKey: privateKey}
+
+		for i := 0; i < 50; i++ {
+			ciphertext := make([]byte, privateKey.Size())
+			rand.Read(ciphertext)
+
+			plaintext, err := rsaObj.DecryptNoPadding(ciphertext)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if expected := decryptNoPaddingPlain(privateKey, ciphertext); !bytes.Equal(plaintext, expected) {
+				t.Fatalf("Expected %d-bit CRT decryption to match the plain one\nciphertext: %x\nexpected: %x\ngot: %x", bits, ciphertext, expected, plaintext)
+			}
+		}
+	}
+}
+
+func benchmarkDecryption(b *testing.B, bits int, decrypt func(rsaObj *RSA, ciphertext []byte)) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		b.Fatalf("Failed to generate test private key: %v", err)
+	}
+	rsaObj := &RSA{privateKey: privateKey}
+
+	ciphertext := make([]byte, privateKey.Size())
+	rand.Read(ciphertext[1:])
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decrypt(rsaObj, ciphertext)
+	}
+}
+
+func BenchmarkDecryptNoPadding1024(b *testing.B) {
+	benchmarkDecryption(b, 1024, func(rsaObj *RSA, ciphertext []byte) { rsaObj.DecryptNoPadding(ciphertext) })
+}
+
+func BenchmarkDecryptNoPaddingPlain1024(b *testing.B) {
+	benchmarkDecryption(b, 1024, func(rsaObj *RSA, ciphertext []byte) { decryptNoPaddingPlain(rsaObj.privateKey, ciphertext) })
+}
+
+func BenchmarkDecryptNoPadding2048(b *testing.B) {
+	benchmarkDecryption(b, 2048, func(rsaObj *RSA, ciphertext []byte) { rsaObj.DecryptNoPadding(ciphertext) })
+}
+
+func BenchmarkDecryptNoPaddingPlain2048(b *testing.B) {
+	benchmarkDecryption(b, 2048, func(rsaObj *RSA, ciphertext []byte) { decryptNoPaddingPlain(rsaObj.privateKey, ciphertext) })
+}
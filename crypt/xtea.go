@@ -19,21 +19,107 @@ func ExpandXteaKey(k [4]uint32) [64]uint32 {
 	return expanded
 }
 
-func XteaEncrypt(data []byte, k [64]uint32) {
-	for i := 0; i < len(k); i += 2 {
-		for offset := 0; offset < len(data); offset += 8 {
+// XteaEncrypt encrypts data in place with each block loaded and stored once. Four blocks are encrypted
+// together, since the rounds of one block depend on each other and independent blocks keep the CPU busy.
+// Any trailing bytes after the last full 8-byte block are left untouched, so callers pad data first.
+func XteaEncrypt(data []byte, k *[64]uint32) {
+	offset := 0
 
-			// Handle left and right parts of the block
-			left := binary.LittleEndian.Uint32(data[offset : offset+4])
-			right := binary.LittleEndian.Uint32(data[offset+4 : offset+8])
+	for ; offset+32 <= len(data); offset += 32 {
+		blocks := data[offset : offset+32]
 
-			// XTEA encryption round
+		left0, right0 := binary.LittleEndian.Uint32(blocks[0:]), binary.LittleEndian.Uint32(blocks[4:])
+		left1, right1 := binary.LittleEndian.Uint32(blocks[8:]), binary.LittleEndian.Uint32(blocks[12:])
+		left2, right2 := binary.LittleEndian.Uint32(blocks[16:]), binary.LittleEndian.Uint32(blocks[20:])
+		left3, right3 := binary.LittleEndian.Uint32(blocks[24:]), binary.LittleEndian.Uint32(blocks[28:])
+
+		for i := 0; i < len(k); i += 2 {
+			left0 += ((right0 << 4) ^ (right0 >> 5)) + right0 ^ k[i]
+			left1 += ((right1 << 4) ^ (right1 >> 5)) + right1 ^ k[i]
+			left2 += ((right2 << 4) ^ (right2 >> 5)) + right2 ^ k[i]
+			left3 += ((right3 << 4) ^ (right3 >> 5)) + right3 ^ k[i]
+
+			right0 += ((left0 << 4) ^ (left0 >> 5)) + left0 ^ k[i+1]
+			right1 += ((left1 << 4) ^ (left1 >> 5)) + left1 ^ k[i+1]
+			right2 += ((left2 << 4) ^ (left2 >> 5)) + left2 ^ k[i+1]
+			right3 += ((left3 << 4) ^ (left3 >> 5)) + left3 ^ k[i+1]
+		}
+
+		binary.LittleEndian.PutUint32(blocks[0:], left0)
+		binary.LittleEndian.PutUint32(blocks[4:], right0)
+		binary.LittleEndian.PutUint32(blocks[8:], left1)
+		binary.LittleEndian.PutUint32(blocks[12:], right1)
+		binary.LittleEndian.PutUint32(blocks[16:], left2)
+		binary.LittleEndian.PutUint32(blocks[20:], right2)
+		binary.LittleEndian.PutUint32(blocks[24:], left3)
+		binary.LittleEndian.PutUint32(blocks[28:], right3)
+	}
+
+	for ; offset+8 <= len(data); offset += 8 {
+		block := data[offset : offset+8]
+
+		// Handle left and right parts of the block
+		left := binary.LittleEndian.Uint32(block[0:4])
+		right := binary.LittleEndian.Uint32(block[4:8])
+
+		// XTEA encryption rounds
+		for i := 0; i < len(k); i += 2 {
 			left += ((right << 4) ^ (right >> 5)) + right ^ k[i]
 			right += ((left << 4) ^ (left >> 5)) + left ^ k[i+1]
+		}
+
+		// Store encrypted result back into data slice
+		binary.LittleEndian.PutUint32(block[0:4], left)
+		binary.LittleEndian.PutUint32(block[4:8], right)
+	}
+}
 
-			// Store encrypted result back into data slice
-			binary.LittleEndian.PutUint32(data[offset:offset+4], left)
-			binary.LittleEndian.PutUint32(data[offset+4:offset+8], right)
+// XteaDecrypt reverses XteaEncrypt in place, running the rounds backwards
+func XteaDecrypt(data []byte, k *[64]uint32) {
+	offset := 0
+
+	for ; offset+32 <= len(data); offset += 32 {
+		blocks := data[offset : offset+32]
+
+		left0, right0 := binary.LittleEndian.Uint32(blocks[0:]), binary.LittleEndian.Uint32(blocks[4:])
+		left1, right1 := binary.LittleEndian.Uint32(blocks[8:]), binary.LittleEndian.Uint32(blocks[12:])
+		left2, right2 := binary.LittleEndian.Uint32(blocks[16:]), binary.LittleEndian.Uint32(blocks[20:])
+		left3, right3 := binary.LittleEndian.Uint32(blocks[24:]), binary.LittleEndian.Uint32(blocks[28:])
+
+		for i := len(k) - 2; i >= 0; i -= 2 {
+			right0 -= ((left0 << 4) ^ (left0 >> 5)) + left0 ^ k[i+1]
+			right1 -= ((left1 << 4) ^ (left1 >> 5)) + left1 ^ k[i+1]
+			right2 -= ((left2 << 4) ^ (left2 >> 5)) + left2 ^ k[i+1]
+			right3 -= ((left3 << 4) ^ (left3 >> 5)) + left3 ^ k[i+1]
+
+			left0 -= ((right0 << 4) ^ (right0 >> 5)) + right0 ^ k[i]
+			left1 -= ((right1 << 4) ^ (right1 >> 5)) + right1 ^ k[i]
+			left2 -= ((right2 << 4) ^ (right2 >> 5)) + right2 ^ k[i]
+			left3 -= ((right3 << 4) ^ (right3 >> 5)) + right3 ^ k[i]
 		}
+
+		binary.LittleEndian.PutUint32(blocks[0:], left0)
+		binary.LittleEndian.PutUint32(blocks[4:], right0)
+		binary.LittleEndian.PutUint32(blocks[8:], left1)
+		binary.LittleEndian.PutUint32(blocks[12:], right1)
+		binary.LittleEndian.PutUint32(blocks[16:], left2)
+		binary.LittleEndian.PutUint32(blocks[20:], right2)
+		binary.LittleEndian.PutUint32(blocks[24:], left3)
+		binary.LittleEndian.PutUint32(blocks[28:], right3)
+	}
+
+	for ; offset+8 <= len(data); offset += 8 {
+		block := data[offset : offset+8]
+
+		left := binary.LittleEndian.Uint32(block[0:4])
+		right := binary.LittleEndian.Uint32(block[4:8])
+
+		for i := len(k) - 2; i >= 0; i -= 2 {
+			right -= ((left << 4) ^ (left >> 5)) + left ^ k[i+1]
+			left -= ((right << 4) ^ (right >> 5)) + right ^ k[i]
+		}
+
+		binary.LittleEndian.PutUint32(block[0:4], left)
+		binary.LittleEndian.PutUint32(block[4:8], right)
 	}
 }
@@ -1,6 +1,9 @@
 package crypt
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"reflect"
 	"testing"
 )
@@ -41,9 +44,144 @@ func TestXteaEncrypt(t *testing.T) {
 		0xb3e12847, 0x5218a200, 0x5218a200, 0xf0501bb9, 0xf0501bb9, 0x8e879572, 0x8e879572, 0x2cbf0f2b,
 		0x2cbf0f2b, 0xcaf688e4, 0xcaf688e4, 0x692e029d, 0x692e029d, 0x07657c56, 0x07657c56, 0xa59cf60f}
 
-	XteaEncrypt(dataToBeEncrypted, encryptionKey)
+	XteaEncrypt(dataToBeEncrypted, &encryptionKey)
 
 	if !reflect.DeepEqual(dataToBeEncrypted, want) {
 		t.Errorf("got %x, wanted %x", dataToBeEncrypted, want)
 	}
 }
+
+// xteaEncryptRoundMajor is the previous implementation, running each round over every block, kept as a reference
+func xteaEncryptRoundMajor(data []byte, k [64]uint32) {
+	for i := 0; i < len(k); i += 2 {
+		for offset := 0; offset < len(data); offset += 8 {
+			left := binary.LittleEndian.Uint32(data[offset : offset+4])
+			right := binary.LittleEndian.Uint32(data[offset+4 : offset+8])
+
+			left += ((right << 4) ^ (right >> 5)) + right ^ k[i]
+			right += ((left << 4) ^ (left >> 5)) + left ^ k[i+1]
+
+			binary.LittleEndian.PutUint32(data[offset:offset+4], left)
+			binary.LittleEndian.PutUint32(data[offset+4:offset+8], right)
+		}
+	}
+}
+
+// hexWords reads big-endian 32-bit words, the notation of the published XTEA test vectors
+func hexWords(t *testing.T, value string) []uint32 {
+	data, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatalf("invalid hex %s: %v", value, err)
+	}
+
+	words := make([]uint32, len(data)/4)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint32(data[i*4:])
+	}
+	return words
+}
+
+func TestXteaKnownAnswers(t *testing.T) {
+	vectors := []struct {
+		key        string
+		plaintext  string
+		ciphertext string
+	}{
+		{"000102030405060708090a0b0c0d0e0f", "4142434445464748", "497df3d072612cb5"},
+		{"000102030405060708090a0b0c0d0e0f", "4141414141414141", "e78f2d13744341d8"},
+		{"00000000000000000000000000000000", "4142434445464748", "a0390589f8b8efa5"},
+		{"00000000000000000000000000000000", "4141414141414141", "ed23375a821a8c2d"},
+	}
+
+	for _, vector := range vectors {
+		keyWords := hexWords(t, vector.key)
+		expandedKey := ExpandXteaKey([4]uint32{keyWords[0], keyWords[1], keyWords[2], keyWords[3]})
+
+		// the protocol stores the block words in little endian
+		plaintextWords := hexWords(t, vector.plaintext)
+		data := make([]byte, 8)
+		binary.LittleEndian.PutUint32(data[0:], plaintextWords[0])
+		binary.LittleEndian.PutUint32(data[4:], plaintextWords[1])
+
+		XteaEncrypt(data, &expandedKey)
+
+		ciphertextWords := hexWords(t, vector.ciphertext)
+		if binary.LittleEndian.Uint32(data[0:]) != ciphertextWords[0] || binary.LittleEndian.Uint32(data[4:]) != ciphertextWords[1] {
+			t.Errorf("key %s, plaintext %s: got %x, wanted %s", vector.key, vector.plaintext, data, vector.ciphertext)
+		}
+
+		XteaDecrypt(data, &expandedKey)
+		if binary.LittleEndian.Uint32(data[0:]) != plaintextWords[0] || binary.LittleEndian.Uint32(data[4:]) != plaintextWords[1] {
+			t.Errorf("key %s: decrypting %s did not give back %s, got %x", vector.key, vector.ciphertext, vector.plaintext, data)
+		}
+	}
+}
+
+func TestXteaEncryptMatchesRoundMajor(t *testing.T) {
+	expandedKey := ExpandXteaKey([4]uint32{0x01234567, 0x89abcdef, 0xfedcba98, 0x76543210})
+
+	// 1048 bytes covers both the four-block loop and the single-block tail
+	data := make([]byte, 1048)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	expected := bytes.Clone(data)
+
+	XteaEncrypt(data, &expandedKey)
+	xteaEncryptRoundMajor(expected, expandedKey)
+
+	if !bytes.Equal(data, expected) {
+		t.Error("block-major encryption does not match the round-major one")
+	}
+}
+
+func TestXteaDecryptRoundTrip(t *testing.T) {
+	expandedKey := ExpandXteaKey([4]uint32{0x1, 0x2, 0x3, 0x4})
+
+	plaintext := []byte("character list, motd and padding\x33\x33\x33")
+	data := bytes.Clone(plaintext)
+
+	XteaEncrypt(data, &expandedKey)
+	if bytes.Equal(data[:32], plaintext[:32]) {
+		t.Fatal("expected data to be encrypted")
+	}
+
+	if !bytes.Equal(data[32:], plaintext[32:]) {
+		t.Errorf("expected trailing bytes after the last full block to be untouched, got %x", data[32:])
+	}
+
+	XteaDecrypt(data, &expandedKey)
+	if !bytes.Equal(data, plaintext) {
+		t.Errorf("got %q, wanted %q", data, plaintext)
+	}
+}
+
+func BenchmarkXteaEncrypt(b *testing.B) {
+	expandedKey := ExpandXteaKey([4]uint32{0x1, 0x2, 0x3, 0x4})
+	data := make([]byte, 1024)
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		XteaEncrypt(data, &expandedKey)
+	}
+}
+
+func BenchmarkXteaEncryptRoundMajor(b *testing.B) {
+	expandedKey := ExpandXteaKey([4]uint32{0x1, 0x2, 0x3, 0x4})
+	data := make([]byte, 1024)
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		xteaEncryptRoundMajor(data, expandedKey)
+	}
+}
+
+func BenchmarkXteaDecrypt(b *testing.B) {
+	expandedKey := ExpandXteaKey([4]uint32{0x1, 0x2, 0x3, 0x4})
+	data := make([]byte, 1024)
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		XteaDecrypt(data, &expandedKey)
+	}
+}
@@ -1,9 +1,11 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"go-opentibia-loginserver/models"
+	"log/slog"
 
 	_ "github.com/go-sql-driver/mysql"
 )
@@ -14,6 +16,34 @@ type DatabaseQuery interface {
 	GetIpBanInfo(database *sql.DB, ip uint32) (models.BanInfo, error)
 	GetAccountInfo(database *sql.DB, accountNumber uint32) (models.AccountInfo, error)
 	GetCharactersList(database *sql.DB, accountId uint32) ([]string, error)
+	// PasswordScheme is the password hashing scheme used by the schema, see crypt.GetPasswordVerifier
+	PasswordScheme() string
+	UpdateAccountPassword(database *sql.DB, accountId uint32, passwordHash string) error
+}
+
+// LoginAttemptRecorder is implemented by query versions that can store login attempts in a table
+type LoginAttemptRecorder interface {
+	InsertLoginAttempt(database *sql.DB, attempt models.LoginAttempt) error
+}
+
+// LastLoginRecorder is implemented by query versions whose schema keeps the last login time and IP of an account
+type LastLoginRecorder interface {
+	UpdateLastLogin(ctx context.Context, database *sql.DB, accountId uint32, ip uint32, timestamp int64) error
+}
+
+// MotdQuery is implemented by query versions whose schema stores the message of the day
+type MotdQuery interface {
+	GetMotd(database *sql.DB) (string, error)
+}
+
+// CastQuery is implemented by query versions whose schema lists the live casts of a cast system
+type CastQuery interface {
+	GetLiveCasts(database *sql.DB) ([]models.LiveCast, error)
+}
+
+// CamQuery is implemented by query versions whose schema lists the recordings of a cam system
+type CamQuery interface {
+	GetCamRecordings(database *sql.DB) ([]models.CamRecording, error)
 }
 
 func CreateDatabaseConnection(user string, password string, host string, port int, databaseName string) (*sql.DB, error) {
@@ -35,7 +65,7 @@ func CreateDatabaseConnection(user string, password string, host string, port in
 		return nil, fmt.Errorf("[createDatabaseConnection] - error querying database version: %s", err)
 	}
 
-	fmt.Printf("[createDatabaseConnection] - connected to %s database (%s); Version: %s\n", DatabaseDriverName, databaseName, version)
+	slog.Info("connected to database", "driver", DatabaseDriverName, "database", databaseName, "version", version)
 
 	return db, nil
 }
@@ -51,6 +81,8 @@ func generateConnectionString(user string, password string, host string, port in
 	)
 }
 
+// GetDatabaseQuery returns the queries of a schema version, or nil when it is unknown. The file version is not
+// a schema, it is created with LoadFileQuery.
 func GetDatabaseQuery(version string) DatabaseQuery {
 	switch version {
 	case "tvp":
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go-opentibia-loginserver/crypt"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/utils"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// QueryVersionFile reads accounts from a YAML or JSON file instead of MySQL, for development and tests
+const QueryVersionFile = "file"
+
+// FileStore is the content of an accounts file, JSON files use the same keys
+type FileStore struct {
+	Accounts []FileAccount `yaml:"accounts"`
+	IpBans   []FileIpBan   `yaml:"ipbans"`
+}
+
+// FileAccount is an account of the accounts file, Password is a hash in any scheme crypt.DetectPasswordScheme knows
+type FileAccount struct {
+	Number        uint32          `yaml:"number"`
+	Password      string          `yaml:"password"`
+	Type          uint32          `yaml:"type"`
+	PremiumEndsAt int64           `yaml:"premiumendsat"`
+	Characters    []FileCharacter `yaml:"characters"`
+}
+
+// FileCharacter is a character of an account, World is the id of its world in gameserver.worlds
+type FileCharacter struct {
+	Name  string `yaml:"name"`
+	World int    `yaml:"world"`
+}
+
+// FileIpBan refuses logins from Ip until ExpiresAt, a unix timestamp, or forever when ExpiresAt is 0
+type FileIpBan struct {
+	Ip        string `yaml:"ip"`
+	Reason    string `yaml:"reason"`
+	ExpiresAt int64  `yaml:"expiresat"`
+	BannedBy  string `yaml:"bannedby"`
+}
+
+// FileQuery answers the queries of the login from an accounts file loaded in memory, the database handle is ignored.
+// Rehashed passwords and last logins are kept in memory only, the file is never written.
+type FileQuery struct {
+	mu         sync.RWMutex
+	accounts   map[uint32]FileAccount
+	ipBans     map[uint32]models.BanInfo
+	lastLogins map[uint32]LastLogin
+}
+
+// LastLogin is the time and IP of the last successful login of an account
+type LastLogin struct {
+	Timestamp int64
+	Ip        uint32
+}
+
+// LoadFileQuery reads the accounts file, which is parsed as YAML, so JSON files are read as well
+func LoadFileQuery(filename string) (*FileQuery, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accounts file %s: %w", filename, err)
+	}
+
+	var store FileStore
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to decode accounts file %s: %w", filename, err)
+	}
+
+	return NewFileQuery(store)
+}
+
+// NewFileQuery indexes the accounts and bans of store, rejecting duplicated account numbers and invalid IPs
+func NewFileQuery(store FileStore) (*FileQuery, error) {
+	query := &FileQuery{
+		accounts:   make(map[uint32]FileAccount, len(store.Accounts)),
+		ipBans:     make(map[uint32]models.BanInfo, len(store.IpBans)),
+		lastLogins: make(map[uint32]LastLogin),
+	}
+
+	for i, account := range store.Accounts {
+		if account.Number == 0 {
+			return nil, fmt.Errorf("accounts[%d]: number is required", i)
+		}
+
+		if _, found := query.accounts[account.Number]; found {
+			return nil, fmt.Errorf("accounts[%d]: number %d is used by another account", i, account.Number)
+		}
+
+		query.accounts[account.Number] = account
+	}
+
+	for i, ban := range store.IpBans {
+		ip, err := utils.IpToUint32(ban.Ip)
+		if err != nil {
+			return nil, fmt.Errorf("ipbans[%d]: %w", i, err)
+		}
+
+		query.ipBans[ip] = models.BanInfo{Author: ban.BannedBy, Reason: ban.Reason, ExpiresAt: ban.ExpiresAt, IsBanned: true}
+	}
+
+	return query, nil
+}
+
+// PasswordScheme is detected from every hash, so the file can mix schemes
+func (q *FileQuery) PasswordScheme() string {
+	return crypt.PasswordSchemeAuto
+}
+
+func (q *FileQuery) GetIpBanInfo(database *sql.DB, ip uint32) (models.BanInfo, error) {
+	banInfo := q.ipBans[ip]
+	if banInfo.ExpiresAt != 0 && banInfo.ExpiresAt <= time.Now().Unix() {
+		return models.BanInfo{}, nil
+	}
+
+	return banInfo, nil
+}
+
+func (q *FileQuery) GetAccountInfo(database *sql.DB, accountNumber uint32) (models.AccountInfo, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	account, found := q.accounts[accountNumber]
+	if !found {
+		return models.AccountInfo{}, nil
+	}
+
+	return models.AccountInfo{
+		Id:            account.Number,
+		PasswordHash:  account.Password,
+		AccountType:   account.Type,
+		PremiumEndsAt: account.PremiumEndsAt,
+	}, nil
+}
+
+func (q *FileQuery) GetCharactersList(database *sql.DB, accountId uint32) ([]string, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	var characterList []string
+	for _, character := range q.accounts[accountId].Characters {
+		characterList = append(characterList, character.Name)
+	}
+
+	sort.Strings(characterList)
+	return characterList, nil
+}
+
+func (q *FileQuery) UpdateAccountPassword(database *sql.DB, accountId uint32, passwordHash string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	account, found := q.accounts[accountId]
+	if !found {
+		return fmt.Errorf("account %d not found", accountId)
+	}
+
+	account.Password = passwordHash
+	q.accounts[accountId] = account
+	return nil
+}
+
+func (q *FileQuery) UpdateLastLogin(ctx context.Context, database *sql.DB, accountId uint32, ip uint32, timestamp int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, found := q.accounts[accountId]; !found {
+		return fmt.Errorf("account %d not found", accountId)
+	}
+
+	q.lastLogins[accountId] = LastLogin{Timestamp: timestamp, Ip: ip}
+	return nil
+}
+
+// LastLogin returns the last login recorded by UpdateLastLogin, found is false when the account has not logged in
+func (q *FileQuery) LastLogin(accountId uint32) (lastLogin LastLogin, found bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	lastLogin, found = q.lastLogins[accountId]
+	return lastLogin, found
+}
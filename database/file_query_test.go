@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeAccountsFile(t *testing.T, name string, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write accounts file: %v", err)
+	}
+	return filename
+}
+
+func TestLoadFileQueryYAML(t *testing.T) {
+	filename := writeAccountsFile(t, "accounts.yaml", `
+accounts:
+  - number: 123456
+    password: e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4
+    type: 5
+    premiumendsat: 1700000000
+    characters:
+      - name: Knight
+        world: 0
+      - name: Druid
+        world: 1
+ipbans:
+  - ip: 10.0.0.1
+    reason: botting
+    expiresat: 4102444800
+    bannedby: GM
+`)
+
+	query, err := LoadFileQuery(filename)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	accountInfo, err := query.GetAccountInfo(nil, 123456)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if accountInfo.Id != 123456 || accountInfo.PasswordHash != "e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4" || accountInfo.AccountType != 5 || accountInfo.PremiumEndsAt != 1700000000 {
+		t.Errorf("Unexpected account info: %+v", accountInfo)
+	}
+
+	characters, _ := query.GetCharactersList(nil, accountInfo.Id)
+	if len(characters) != 2 || characters[0] != "Druid" || characters[1] != "Knight" {
+		t.Errorf("Expected characters sorted by name, got %v", characters)
+	}
+
+	if druid := query.accounts[123456].Characters[1]; druid.Name != "Druid" || druid.World != 1 {
+		t.Errorf("Expected Druid on world 1, got %+v", druid)
+	}
+
+	banInfo, _ := query.GetIpBanInfo(nil, 0x0100000A)
+	if !banInfo.IsBanned || banInfo.Reason != "botting" || banInfo.Author != "GM" {
+		t.Errorf("Expected 10.0.0.1 to be banned, got %+v", banInfo)
+	}
+
+	if banInfo, _ := query.GetIpBanInfo(nil, 0x0200000A); banInfo.IsBanned {
+		t.Error("Expected 10.0.0.2 not to be banned")
+	}
+
+	if accountInfo, _ := query.GetAccountInfo(nil, 654321); accountInfo.Id != 0 {
+		t.Errorf("Expected an empty account info for an unknown account, got %+v", accountInfo)
+	}
+}
+
+func TestLoadFileQueryJSON(t *testing.T) {
+	filename := writeAccountsFile(t, "accounts.json", `{"accounts": [{"number": 1, "password": "secret", "characters": [{"name": "Knight"}]}]}`)
+
+	query, err := LoadFileQuery(filename)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if characters, _ := query.GetCharactersList(nil, 1); len(characters) != 1 || characters[0] != "Knight" {
+		t.Errorf("Unexpected characters: %v", characters)
+	}
+}
+
+func TestNewFileQueryInvalid(t *testing.T) {
+	tests := []FileStore{
+		{Accounts: []FileAccount{{Number: 0}}},
+		{Accounts: []FileAccount{{Number: 1}, {Number: 1}}},
+		{IpBans: []FileIpBan{{Ip: "not-an-ip"}}},
+	}
+
+	for _, store := range tests {
+		if _, err := NewFileQuery(store); err == nil {
+			t.Errorf("Expected error for %+v, got none", store)
+		}
+	}
+}
+
+func TestFileQueryUpdateAccountPassword(t *testing.T) {
+	query, _ := NewFileQuery(FileStore{Accounts: []FileAccount{{Number: 1, Password: "old"}}})
+
+	if err := query.UpdateAccountPassword(nil, 1, "new"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if accountInfo, _ := query.GetAccountInfo(nil, 1); accountInfo.PasswordHash != "new" {
+		t.Errorf("Expected the updated hash, got %q", accountInfo.PasswordHash)
+	}
+
+	if err := query.UpdateAccountPassword(nil, 2, "new"); err == nil {
+		t.Error("Expected error for an unknown account, got none")
+	}
+}
+
+func TestFileQueryExpiredIpBan(t *testing.T) {
+	query, err := NewFileQuery(FileStore{IpBans: []FileIpBan{
+		{Ip: "10.0.0.1", Reason: "expired", ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+		{Ip: "10.0.0.2", Reason: "active", ExpiresAt: time.Now().Add(time.Hour).Unix()},
+		{Ip: "10.0.0.3", Reason: "permanent"},
+	}})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if banInfo, _ := query.GetIpBanInfo(nil, 0x0100000A); banInfo.IsBanned {
+		t.Errorf("Expected the expired ban not to refuse logins, got %+v", banInfo)
+	}
+
+	for _, ip := range []uint32{0x0200000A, 0x0300000A} {
+		if banInfo, _ := query.GetIpBanInfo(nil, ip); !banInfo.IsBanned {
+			t.Errorf("Expected %08x to be banned", ip)
+		}
+	}
+}
+
+func TestFileQueryUpdateLastLogin(t *testing.T) {
+	query, _ := NewFileQuery(FileStore{Accounts: []FileAccount{{Number: 1}}})
+
+	if err := query.UpdateLastLogin(context.Background(), nil, 1, 0x0100000A, 1700000000); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if lastLogin, found := query.LastLogin(1); !found || lastLogin != (LastLogin{Timestamp: 1700000000, Ip: 0x0100000A}) {
+		t.Errorf("Unexpected last login: %+v", lastLogin)
+	}
+
+	if err := query.UpdateLastLogin(context.Background(), nil, 2, 0x0100000A, 1700000000); err == nil {
+		t.Error("Expected error for an unknown account, got none")
+	}
+}
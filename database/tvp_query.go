@@ -1,13 +1,19 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"go-opentibia-loginserver/crypt"
 	"go-opentibia-loginserver/models"
 )
 
 type TvpQuery struct{}
 
+func (q *TvpQuery) PasswordScheme() string {
+	return crypt.PasswordSchemeSHA1
+}
+
 func (q *TvpQuery) GetIpBanInfo(database *sql.DB, ip uint32) (models.BanInfo, error) {
 	var banInfo models.BanInfo
 	statement := fmt.Sprintf("SELECT `reason`, `expires_at`, `banned_by` FROM `ip_bans` WHERE `ip` = %d", ip)
@@ -29,7 +35,7 @@ func (q *TvpQuery) GetAccountInfo(database *sql.DB, accountNumber uint32) (model
 	var accountInfo models.AccountInfo
 	statement := fmt.Sprintf("SELECT `id`, `password`, `type`, `premium_ends_at` FROM `accounts` WHERE `id` = %d", accountNumber)
 
-	err := database.QueryRow(statement).Scan(&accountInfo.Id, &accountInfo.PasswordSHA1, &accountInfo.AccountType, &accountInfo.PremiumEndsAt)
+	err := database.QueryRow(statement).Scan(&accountInfo.Id, &accountInfo.PasswordHash, &accountInfo.AccountType, &accountInfo.PremiumEndsAt)
 	if err != nil && err != sql.ErrNoRows {
 		return accountInfo, err
 	}
@@ -37,6 +43,84 @@ func (q *TvpQuery) GetAccountInfo(database *sql.DB, accountNumber uint32) (model
 	return accountInfo, nil
 }
 
+func (q *TvpQuery) UpdateAccountPassword(database *sql.DB, accountId uint32, passwordHash string) error {
+	_, err := database.Exec("UPDATE `accounts` SET `password` = ? WHERE `id` = ?", passwordHash, accountId)
+	return err
+}
+
+func (q *TvpQuery) InsertLoginAttempt(database *sql.DB, attempt models.LoginAttempt) error {
+	_, err := database.Exec(
+		"INSERT INTO `login_attempts` (`ip`, `account_id`, `client_version`, `client_os`, `outcome`, `created_at`) VALUES (?, ?, ?, ?, ?, ?)",
+		attempt.Ip, attempt.AccountNumber, attempt.ClientVersion, attempt.ClientOs, attempt.Outcome, attempt.Timestamp,
+	)
+	return err
+}
+
+func (q *TvpQuery) UpdateLastLogin(ctx context.Context, database *sql.DB, accountId uint32, ip uint32, timestamp int64) error {
+	_, err := database.ExecContext(ctx, "UPDATE `accounts` SET `last_login_at` = ?, `last_login_ip` = ? WHERE `id` = ?", timestamp, ip, accountId)
+	return err
+}
+
+func (q *TvpQuery) GetMotd(database *sql.DB) (string, error) {
+	var motd string
+
+	err := database.QueryRow("SELECT `value` FROM `server_config` WHERE `config` = 'motd'").Scan(&motd)
+	if err != nil && err != sql.ErrNoRows {
+		return motd, err
+	}
+
+	return motd, nil
+}
+
+// GetLiveCasts lists the most watched casts first, no more than a character list can count
+func (q *TvpQuery) GetLiveCasts(database *sql.DB) ([]models.LiveCast, error) {
+	var casts []models.LiveCast
+
+	rows, err := database.Query("SELECT `players`.`name`, `live_casts`.`password`, `live_casts`.`spectators` FROM `live_casts` INNER JOIN `players` ON `players`.`id` = `live_casts`.`player_id` ORDER BY `live_casts`.`spectators` DESC, `players`.`name` ASC LIMIT 255")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var cast models.LiveCast
+
+		err := rows.Scan(&cast.PlayerName, &cast.Password, &cast.Spectators)
+		if err != nil {
+			return nil, err
+		}
+
+		casts = append(casts, cast)
+	}
+
+	return casts, rows.Err()
+}
+
+func (q *TvpQuery) GetCamRecordings(database *sql.DB) ([]models.CamRecording, error) {
+	var recordings []models.CamRecording
+
+	rows, err := database.Query("SELECT `id`, `player_name`, `recorded_at`, `duration` FROM `cam_recordings` ORDER BY `recorded_at` DESC")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var recording models.CamRecording
+
+		err := rows.Scan(&recording.Id, &recording.PlayerName, &recording.RecordedAt, &recording.Duration)
+		if err != nil {
+			return nil, err
+		}
+
+		recordings = append(recordings, recording)
+	}
+
+	return recordings, rows.Err()
+}
+
 func (q *TvpQuery) GetCharactersList(database *sql.DB, accountId uint32) ([]string, error) {
 	var characterList []string
 
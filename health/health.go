@@ -0,0 +1,175 @@
+package health
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"go-opentibia-loginserver/logging"
+	"go-opentibia-loginserver/metrics"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	ProbeTCP    = "tcp"
+	ProbeStatus = "status"
+
+	DefaultInterval = 10 * time.Second
+	DefaultTimeout  = 2 * time.Second
+)
+
+// statusRequest asks an OT server for its XML status (protocol 0xFF, request 0xFF "info")
+var statusRequest = []byte{0x06, 0x00, 0xFF, 0xFF, 'i', 'n', 'f', 'o'}
+
+// Probe checks whether a game server answers on address
+type Probe func(address string, timeout time.Duration) error
+
+// Target is a game server address to be checked, labelled by the world it belongs to
+type Target struct {
+	World   string
+	Address string
+}
+
+func GetProbe(name string) Probe {
+	switch name {
+	case "", ProbeTCP:
+		return TCPProbe
+	case ProbeStatus:
+		return StatusProbe
+	}
+
+	return nil
+}
+
+// TCPProbe only checks that the game server accepts connections
+func TCPProbe(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// StatusProbe asks the game server for its status, which also catches a server that accepts connections but is stuck
+func StatusProbe(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(statusRequest); err != nil {
+		return fmt.Errorf("failed to send status request: %w", err)
+	}
+
+	var size uint16
+	if err := binary.Read(conn, binary.LittleEndian, &size); err != nil {
+		return fmt.Errorf("failed to read status response: %w", err)
+	}
+
+	response := make([]byte, size)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return fmt.Errorf("failed to read status response: %w", err)
+	}
+
+	if !bytes.Contains(response, []byte("<tsqp")) {
+		return fmt.Errorf("unexpected status response")
+	}
+
+	return nil
+}
+
+// Checker periodically probes the game servers and remembers which addresses are offline.
+// An address that was never checked is considered online.
+type Checker struct {
+	probe    Probe
+	interval time.Duration
+	timeout  time.Duration
+	targets  func() []Target
+
+	mu      sync.RWMutex
+	offline map[string]bool
+}
+
+// NewChecker creates a checker for the targets returned by targets, which is called before every round
+// so config reloads are picked up
+func NewChecker(probe Probe, interval time.Duration, timeout time.Duration, targets func() []Target) *Checker {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Checker{
+		probe:    probe,
+		interval: interval,
+		timeout:  timeout,
+		targets:  targets,
+		offline:  make(map[string]bool),
+	}
+}
+
+// Start checks every target now and then on every interval, in the background
+func (c *Checker) Start() {
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		for {
+			c.CheckAll()
+			<-ticker.C
+		}
+	}()
+}
+
+// CheckAll probes every target concurrently and waits for the results
+func (c *Checker) CheckAll() {
+	var wg sync.WaitGroup
+
+	for _, target := range c.targets() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.check(target)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (c *Checker) check(target Target) {
+	err := c.probe(target.Address, c.timeout)
+	online := err == nil
+	metrics.ObserveWorldOnline(target.World, target.Address, online)
+
+	c.mu.Lock()
+	wasOffline := c.offline[target.Address]
+	c.offline[target.Address] = !online
+	c.mu.Unlock()
+
+	if !online && !wasOffline {
+		slog.Warn("world is offline", "world", target.World, "address", target.Address, logging.KeyError, err)
+	} else if online && wasOffline {
+		slog.Info("world is back online", "world", target.World, "address", target.Address)
+	}
+}
+
+// IsOnline tells whether address answered the last check, a nil checker reports every address online
+func (c *Checker) IsOnline(address string) bool {
+	if c == nil {
+		return true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return !c.offline[address]
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T, handle func(conn net.Conn)) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			handle(conn)
+			conn.Close()
+		}
+	}()
+
+	return listener
+}
+
+func TestCheckerTracksOfflineAddresses(t *testing.T) {
+	listener := listen(t, func(conn net.Conn) {})
+	address := listener.Addr().String()
+
+	checker := NewChecker(TCPProbe, time.Minute, time.Second, func() []Target {
+		return []Target{{World: "Test", Address: address}}
+	})
+
+	checker.CheckAll()
+	if !checker.IsOnline(address) {
+		t.Error("Expected world to be online")
+	}
+
+	listener.Close()
+	checker.CheckAll()
+	if checker.IsOnline(address) {
+		t.Error("Expected world to be offline after its listener was closed")
+	}
+}
+
+func TestNilCheckerIsOnline(t *testing.T) {
+	var checker *Checker
+
+	if !checker.IsOnline("127.0.0.1:7172") {
+		t.Error("Expected a nil checker to report every address online")
+	}
+}
+
+func TestStatusProbe(t *testing.T) {
+	listener := listen(t, func(conn net.Conn) {
+		request := make([]byte, len(statusRequest))
+		io.ReadFull(conn, request)
+
+		response := []byte(`<?xml version="1.0"?><tsqp version="1.0"><serverinfo servername="Test"/></tsqp>`)
+		binary.Write(conn, binary.LittleEndian, uint16(len(response)))
+		conn.Write(response)
+	})
+	defer listener.Close()
+
+	if err := StatusProbe(listener.Addr().String(), time.Second); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestStatusProbeUnexpectedResponse(t *testing.T) {
+	listener := listen(t, func(conn net.Conn) {
+		conn.Write([]byte{0x02, 0x00, 'n', 'o'})
+	})
+	defer listener.Close()
+
+	if err := StatusProbe(listener.Addr().String(), time.Second); err == nil {
+		t.Error("Expected an error for a server that does not answer the status protocol, got none")
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"go-opentibia-loginserver/crypt"
+	"io/fs"
+	"os"
+)
+
+// runKeygen creates a new RSA key for the login protocol and prints the modulus clients must be patched with
+func runKeygen(args []string) int {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	bits := flags.Int("bits", crypt.MinRSAKeyBits, "key size in bits")
+	out := flags.String("out", "key.pem", "file to write the private key to")
+	force := flags.Bool("force", false, "overwrite the file when it exists")
+	flags.Parse(args)
+
+	if _, err := os.Stat(*out); err == nil && !*force {
+		fmt.Printf("%s already exists, use --force to overwrite it\n", *out)
+		return 1
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("could not check %s: %s\n", *out, err)
+		return 1
+	}
+
+	privateKey, err := crypt.GenerateRSAKey(*bits)
+	if err != nil {
+		fmt.Printf("could not generate key: %s\n", err)
+		return 1
+	}
+
+	if err := os.WriteFile(*out, crypt.EncodeRSAPrivateKeyPEM(privateKey), 0600); err != nil {
+		fmt.Printf("could not write key: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("wrote %d-bit key to %s\n", privateKey.N.BitLen(), *out)
+	fmt.Printf("public exponent: %d\n", privateKey.E)
+	fmt.Printf("modulus (decimal, for client patchers and OTClient):\n%s\n", privateKey.N.String())
+	return 0
+}
+
+// runInspect loads a key the way the server does, checks that it decrypts what a client encrypts with it
+// and prints its modulus
+func runInspect(args []string) int {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	modulusOnly := flags.Bool("modulus", false, "only print the decimal modulus")
+	passphraseFile := flags.String("passphrase-file", "", "file holding the passphrase of an encrypted key")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: inspect [--modulus] [--passphrase-file file] <key.pem>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	keyFile := flags.Arg(0)
+
+	passphrase, err := readPassphrase("", *passphraseFile)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 1
+	}
+
+	decrypter, err := crypt.NewRSADecrypterWithPassphrase(keyFile, passphrase)
+	if err != nil {
+		fmt.Printf("invalid key: %s\n", err)
+		return 1
+	}
+	publicKey := decrypter.PublicKey()
+
+	// the modulus alone is patched into clients, a key with another exponent can not decrypt their logins
+	if publicKey.E != crypt.ClientPublicExponent {
+		fmt.Printf("invalid key: public exponent is %d, clients use %d\n", publicKey.E, crypt.ClientPublicExponent)
+		return 1
+	}
+
+	if *modulusOnly {
+		fmt.Println(publicKey.N.String())
+		return 0
+	}
+
+	fmt.Printf("key file: %s\n", keyFile)
+	fmt.Printf("size: %d bits\n", publicKey.N.BitLen())
+	fmt.Printf("public exponent: %d\n", publicKey.E)
+	fmt.Printf("modulus (decimal):\n%s\n", publicKey.N.String())
+
+	if err := checkKeyRoundTrip(decrypter); err != nil {
+		fmt.Printf("key can not be used for logins: %s\n", err)
+		return 1
+	}
+
+	fmt.Println("key is valid")
+	return 0
+}
+
+// checkKeyRoundTrip encrypts a random login block, starting with the zero byte clients send, and decrypts it back
+func checkKeyRoundTrip(decrypter *crypt.RSA) error {
+	plaintext := make([]byte, decrypter.PublicKey().Size())
+	if _, err := rand.Read(plaintext[1:]); err != nil {
+		return err
+	}
+	plaintext[1] &= 0x7F // keep the block below the modulus
+
+	ciphertext, err := crypt.EncryptNoPadding(decrypter.PublicKey(), plaintext)
+	if err != nil {
+		return err
+	}
+
+	decrypted, err := decrypter.DecryptNoPadding(ciphertext)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(decrypted, plaintext) {
+		return fmt.Errorf("decrypted block does not match the encrypted one")
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"go-opentibia-loginserver/crypt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newExponentTestKey builds a 1024-bit key with public exponent e, which rsa.GenerateKey does not allow
+func newExponentTestKey(t *testing.T, e int) *rsa.PrivateKey {
+	one := big.NewInt(1)
+
+	for {
+		p, err := rand.Prime(rand.Reader, 512)
+		if err != nil {
+			t.Fatalf("Failed to generate prime: %v", err)
+		}
+		q, err := rand.Prime(rand.Reader, 512)
+		if err != nil {
+			t.Fatalf("Failed to generate prime: %v", err)
+		}
+
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		d := new(big.Int).ModInverse(big.NewInt(int64(e)), phi)
+		n := new(big.Int).Mul(p, q)
+		if p.Cmp(q) == 0 || d == nil || n.BitLen() != 1024 {
+			continue
+		}
+
+		key := &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: n, E: e}, D: d, Primes: []*big.Int{p, q}}
+		key.Precompute()
+		if err := key.Validate(); err != nil {
+			t.Fatalf("Failed to build test key: %v", err)
+		}
+
+		return key
+	}
+}
+
+func TestRunInspectPublicExponent(t *testing.T) {
+	tests := []struct {
+		name     string
+		exponent int
+		expected int
+	}{
+		{"client exponent", crypt.ClientPublicExponent, 0},
+		{"other exponent", 3, 1},
+	}
+
+	for _, test := range tests {
+		keyFile := filepath.Join(t.TempDir(), "key.pem")
+		if err := os.WriteFile(keyFile, crypt.EncodeRSAPrivateKeyPEM(newExponentTestKey(t, test.exponent)), 0600); err != nil {
+			t.Fatalf("Failed to write key file: %v", err)
+		}
+
+		if code := runInspect([]string{keyFile}); code != test.expected {
+			t.Errorf("%s: expected exit code %d, got %d", test.name, test.expected, code)
+		}
+
+		if code := runInspect([]string{"--modulus", keyFile}); code != test.expected {
+			t.Errorf("%s: expected exit code %d with --modulus, got %d", test.name, test.expected, code)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"go-opentibia-loginserver/client"
+	"go-opentibia-loginserver/loadtest"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"time"
+)
+
+// runLoadTest sends logins from many connections at once and prints throughput, latencies and outcomes
+func runLoadTest(args []string) int {
+	flags := flag.NewFlagSet("load-test", flag.ExitOnError)
+	address := flags.String("address", "127.0.0.1:7171", "login server address")
+	accountsFile := flags.String("accounts", "", "file with the account pool, one number:password per line")
+	account := flags.Uint("account", 0, "account number, when no account pool is given")
+	password := flags.String("password", "", "account password, when no account pool is given")
+	keyFlags := addPublicKeyFlags(flags)
+	protocolVersion := flags.Uint("protocol", uint(client.DefaultProtocolVersion), "protocol version sent by the clients")
+	concurrency := flags.Int("concurrency", loadtest.DefaultConcurrency, "connections open at the same time")
+	duration := flags.Duration("duration", 0, "how long to run, stops earlier when --logins were sent")
+	logins := flags.Int("logins", 0, "number of logins to send, 0 to run for --duration")
+	wrongPasswordRatio := flags.Float64("wrong-password-ratio", 0, "share of logins sent with a wrong password, from 0 to 1")
+	slowRatio := flags.Float64("slow-ratio", 0, "share of clients that wait --slow-delay before sending the login")
+	truncatedRatio := flags.Float64("truncated-ratio", 0, "share of clients that send a truncated login packet")
+	slowDelay := flags.Duration("slow-delay", loadtest.DefaultSlowDelay, "how long slow clients wait before sending the login")
+	timeout := flags.Duration("timeout", client.DefaultTimeout, "connection timeout")
+	flags.Parse(args)
+
+	publicKey, err := keyFlags.load()
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 2
+	}
+
+	accounts := []loadtest.Account{{Number: uint32(*account), Password: *password}}
+	if *accountsFile != "" {
+		if accounts, err = readAccountPool(*accountsFile); err != nil {
+			fmt.Printf("could not read accounts: %s\n", err)
+			return 2
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := loadtest.Run(ctx, loadtest.Options{
+		Client: client.Options{
+			Address:         *address,
+			PublicKey:       publicKey,
+			ProtocolVersion: uint16(*protocolVersion),
+			Timeout:         *timeout,
+		},
+		Accounts:           accounts,
+		Concurrency:        *concurrency,
+		Duration:           *duration,
+		Logins:             *logins,
+		WrongPasswordRatio: *wrongPasswordRatio,
+		SlowRatio:          *slowRatio,
+		TruncatedRatio:     *truncatedRatio,
+		SlowDelay:          *slowDelay,
+	})
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return 2
+	}
+
+	printLoadReport(report)
+	return 0
+}
+
+func readAccountPool(filename string) ([]loadtest.Account, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	accounts, err := loadtest.ReadAccounts(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("%s has no accounts", filename)
+	}
+
+	return accounts, nil
+}
+
+func printLoadReport(report *loadtest.Report) {
+	fmt.Printf("%d logins in %s (%.1f logins/s)\n", report.Logins, report.Elapsed.Round(time.Millisecond), report.Throughput())
+	fmt.Printf("latency of %d answered logins: p50 %s, p90 %s, p99 %s, max %s\n", report.Answered(),
+		report.Percentile(50), report.Percentile(90), report.Percentile(99), report.Percentile(100))
+
+	fmt.Println("outcomes:")
+	printCounts(report.Outcomes)
+
+	fmt.Println("clients:")
+	printCounts(report.Behaviors)
+}
+
+// printCounts prints the most frequent keys first
+func printCounts(counts map[string]int) {
+	keys := slices.SortedFunc(maps.Keys(counts), func(a string, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), cmp.Compare(a, b))
+	})
+
+	for _, key := range keys {
+		fmt.Printf("  %8d  %s\n", counts[key], key)
+	}
+}
@@ -0,0 +1,320 @@
+package loadtest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"go-opentibia-loginserver/client"
+	"go-opentibia-loginserver/protocol"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	// BehaviorNormal sends the whole login packet at once, like a client
+	BehaviorNormal = "normal"
+	// BehaviorSlow connects and waits SlowDelay before sending the login packet
+	BehaviorSlow = "slow"
+	// BehaviorTruncated sends the login packet cut at any byte and stops writing
+	BehaviorTruncated = "truncated"
+
+	OutcomeOk = "ok"
+
+	DefaultConcurrency = 10
+	DefaultSlowDelay   = 2 * time.Second
+)
+
+type Account struct {
+	Number   uint32
+	Password string
+}
+
+// Options describe the load, the ratios are the share of logins, from 0 to 1, sent with a wrong password,
+// by slow clients and as truncated packets
+type Options struct {
+	Client             client.Options
+	Accounts           []Account
+	Concurrency        int
+	Duration           time.Duration
+	Logins             int
+	WrongPasswordRatio float64
+	SlowRatio          float64
+	TruncatedRatio     float64
+	SlowDelay          time.Duration
+}
+
+// Report is the result of a run, Outcomes counts every login by what the server answered
+// ("ok", "refused: <message>" or the connection error)
+type Report struct {
+	Elapsed   time.Duration
+	Logins    int
+	Outcomes  map[string]int
+	Behaviors map[string]int
+
+	// latencies of the logins sent at once that got an answer, sorted
+	latencies []time.Duration
+}
+
+// Throughput is the number of logins handled per second
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Logins) / r.Elapsed.Seconds()
+}
+
+// Percentile returns the latency under which p percent (0-100) of the answered logins were handled
+func (r *Report) Percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+
+	rank := int(p / 100 * float64(len(r.latencies)))
+	if rank >= len(r.latencies) {
+		rank = len(r.latencies) - 1
+	}
+
+	return r.latencies[rank]
+}
+
+// Answered is the number of logins the latency percentiles are computed from
+func (r *Report) Answered() int {
+	return len(r.latencies)
+}
+
+type attempt struct {
+	behavior string
+	outcome  string
+	latency  time.Duration
+	answered bool
+}
+
+// Run sends logins from Concurrency connections at a time until Logins were sent, Duration passed
+// or ctx is done, whichever comes first
+func Run(ctx context.Context, options Options) (*Report, error) {
+	if len(options.Accounts) == 0 {
+		return nil, fmt.Errorf("no accounts to login with")
+	}
+
+	if options.Logins <= 0 && options.Duration <= 0 {
+		return nil, fmt.Errorf("either the number of logins or the duration must be set")
+	}
+
+	if options.WrongPasswordRatio < 0 || options.SlowRatio < 0 || options.TruncatedRatio < 0 || options.SlowRatio+options.TruncatedRatio > 1 {
+		return nil, fmt.Errorf("ratios must be positive and the slow and truncated ratios must add up to at most 1")
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	if options.SlowDelay <= 0 {
+		options.SlowDelay = DefaultSlowDelay
+	}
+
+	if options.Client.Timeout <= 0 {
+		options.Client.Timeout = client.DefaultTimeout
+	}
+
+	if options.Client.ProtocolVersion == 0 {
+		options.Client.ProtocolVersion = client.DefaultProtocolVersion
+	}
+
+	if options.Client.ClientOs == 0 {
+		options.Client.ClientOs = client.DefaultClientOs
+	}
+
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+
+	report := &Report{Outcomes: make(map[string]int), Behaviors: make(map[string]int)}
+	var reportMutex sync.Mutex
+	var sent atomic.Int64
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for ctx.Err() == nil {
+				if options.Logins > 0 && sent.Add(1) > int64(options.Logins) {
+					return
+				}
+
+				result := runAttempt(ctx, &options)
+				if !result.answered && ctx.Err() != nil {
+					return // interrupted by the end of the run, not by the server
+				}
+
+				reportMutex.Lock()
+				report.Logins++
+				report.Outcomes[result.outcome]++
+				report.Behaviors[result.behavior]++
+				if result.answered && result.behavior == BehaviorNormal {
+					report.latencies = append(report.latencies, result.latency)
+				}
+				reportMutex.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	slices.Sort(report.latencies)
+
+	return report, nil
+}
+
+func runAttempt(ctx context.Context, options *Options) attempt {
+	result := attempt{behavior: pickBehavior(options)}
+
+	account := options.Accounts[rand.IntN(len(options.Accounts))]
+	password := account.Password
+	if rand.Float64() < options.WrongPasswordRatio {
+		password += "-wrong"
+	}
+
+	xteaKey, err := client.NewXteaKey()
+	if err != nil {
+		result.outcome = classifyError(err)
+		return result
+	}
+
+	loginPacket, err := client.BuildLoginPacket(options.Client.PublicKey, protocol.LoginRequest{
+		ClientOs:        options.Client.ClientOs,
+		ProtocolVersion: options.Client.ProtocolVersion,
+		XteaKey:         xteaKey,
+		AccountNumber:   account.Number,
+		Password:        password,
+	})
+	if err != nil {
+		result.outcome = classifyError(err)
+		return result
+	}
+
+	start := time.Now()
+	dialer := net.Dialer{Timeout: options.Client.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", options.Client.Address)
+	if err != nil {
+		result.outcome = classifyError(err)
+		return result
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(options.Client.Timeout + options.SlowDelay))
+
+	switch result.behavior {
+	case BehaviorSlow:
+		select {
+		case <-time.After(options.SlowDelay):
+		case <-ctx.Done():
+			result.outcome = classifyError(ctx.Err())
+			return result
+		}
+	case BehaviorTruncated:
+		// the cut may fall anywhere, inside the size header and the fixed fields as well as inside the RSA block
+		loginPacket = loginPacket[:1+rand.IntN(len(loginPacket)-1)]
+	}
+
+	if _, err := conn.Write(loginPacket); err != nil {
+		result.outcome = classifyError(err)
+		return result
+	}
+
+	if result.behavior == BehaviorTruncated {
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			tcpConn.CloseWrite()
+		}
+	}
+
+	response, err := client.ReadResponse(conn, xteaKey)
+	if err != nil {
+		result.outcome = classifyError(err)
+		return result
+	}
+
+	result.latency = time.Since(start)
+	result.answered = true
+	if response.Error != "" {
+		result.outcome = "refused: " + response.Error
+	} else {
+		result.outcome = OutcomeOk
+	}
+
+	return result
+}
+
+func pickBehavior(options *Options) string {
+	roll := rand.Float64()
+
+	if roll < options.SlowRatio {
+		return BehaviorSlow
+	}
+
+	if roll < options.SlowRatio+options.TruncatedRatio {
+		return BehaviorTruncated
+	}
+
+	return BehaviorNormal
+}
+
+// classifyError groups connection errors so the report does not list one line per address and port
+func classifyError(err error) string {
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection closed"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return "connection reset"
+	}
+
+	return "error: " + err.Error()
+}
+
+// ReadAccounts reads an account pool, one "number:password" per line, ignoring empty lines and # comments
+func ReadAccounts(reader io.Reader) ([]Account, error) {
+	var accounts []Account
+
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		numberText, password, found := strings.Cut(text, ":")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected number:password", line)
+		}
+
+		number, err := strconv.ParseUint(strings.TrimSpace(numberText), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid account number %q", line, numberText)
+		}
+
+		accounts = append(accounts, Account{Number: uint32(number), Password: password})
+	}
+
+	return accounts, scanner.Err()
+}
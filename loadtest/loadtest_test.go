@@ -0,0 +1,160 @@
+package loadtest
+
+import (
+	"context"
+	"crypto/rsa"
+	"go-opentibia-loginserver/client"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/crypt"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/packet"
+	"go-opentibia-loginserver/protocol"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve answers logins the way the login server does, accepting the password "secret" for every account
+func serve(t *testing.T) (string, *rsa.PublicKey) {
+	privateKey, err := crypt.GenerateRSAKey(1024)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+
+	key := crypt.NewRSADecrypterFromKey("key.pem", privateKey)
+	parser := protocol.NewLoginParser(key)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				incoming := packet.NewIncoming(1024)
+				length, err := conn.Read(incoming.PeekBuffer())
+				if err != nil || length < 3 {
+					return
+				}
+				incoming.Resize(length)
+				incoming.GetUint16() // message size
+				incoming.GetUint8()  // opcode
+
+				request, err := parser.ParseLogin(incoming)
+				if err != nil {
+					return
+				}
+
+				if request.Password != "secret" {
+					protocol.SendClientError(conn, request.XteaKey, "Account number or password is not correct.")
+					return
+				}
+
+				accountInfo := &models.AccountInfo{Characters: []string{"Knight"}}
+				protocol.SendClientMotdAndCharacterList(conn, request.XteaKey, models.Motd{}, accountInfo, &config.World{Name: "Test"}, 0x0100007F, 7172)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), key.PublicKey()
+}
+
+func TestRun(t *testing.T) {
+	address, publicKey := serve(t)
+
+	report, err := Run(context.Background(), Options{
+		Client:             client.Options{Address: address, PublicKey: publicKey, Timeout: time.Second},
+		Accounts:           []Account{{Number: 1, Password: "secret"}, {Number: 2, Password: "secret"}},
+		Concurrency:        4,
+		Logins:             60,
+		WrongPasswordRatio: 0.3,
+		SlowRatio:          0.2,
+		TruncatedRatio:     0.2,
+		SlowDelay:          10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if report.Logins != 60 {
+		t.Errorf("Expected 60 logins, got %d", report.Logins)
+	}
+
+	for outcome := range report.Outcomes {
+		if outcome != OutcomeOk && outcome != "refused: Account number or password is not correct." && outcome != "connection closed" {
+			t.Errorf("Unexpected outcome %q", outcome)
+		}
+	}
+
+	if report.Outcomes[OutcomeOk] == 0 || report.Behaviors[BehaviorNormal] == 0 {
+		t.Errorf("Expected successful normal logins, got %v %v", report.Outcomes, report.Behaviors)
+	}
+
+	if report.Outcomes["connection closed"] != report.Behaviors[BehaviorTruncated] {
+		t.Errorf("Expected every truncated login to be closed by the server, got %v %v", report.Outcomes, report.Behaviors)
+	}
+
+	if report.Answered() == 0 || report.Percentile(50) <= 0 || report.Percentile(50) > report.Percentile(100) {
+		t.Errorf("Unexpected latencies: p50 %s, max %s from %d logins", report.Percentile(50), report.Percentile(100), report.Answered())
+	}
+}
+
+func TestRunInvalidOptions(t *testing.T) {
+	tests := []Options{
+		{Logins: 1},
+		{Accounts: []Account{{Number: 1}}},
+		{Accounts: []Account{{Number: 1}}, Logins: 1, SlowRatio: 0.6, TruncatedRatio: 0.6},
+	}
+
+	for _, options := range tests {
+		if _, err := Run(context.Background(), options); err == nil {
+			t.Errorf("Expected error for %+v, got none", options)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	report := &Report{}
+	for i := 1; i <= 100; i++ {
+		report.latencies = append(report.latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	if p := report.Percentile(50); p != 51*time.Millisecond {
+		t.Errorf("Expected p50 of 51ms, got %s", p)
+	}
+
+	if p := report.Percentile(99); p != 100*time.Millisecond {
+		t.Errorf("Expected p99 of 100ms, got %s", p)
+	}
+
+	if p := (&Report{}).Percentile(50); p != 0 {
+		t.Errorf("Expected 0 without latencies, got %s", p)
+	}
+}
+
+func TestReadAccounts(t *testing.T) {
+	accounts, err := ReadAccounts(strings.NewReader("# test accounts\n123456:secret\n\n654321:pass:word\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(accounts) != 2 || accounts[0] != (Account{123456, "secret"}) || accounts[1] != (Account{654321, "pass:word"}) {
+		t.Errorf("Unexpected accounts: %+v", accounts)
+	}
+
+	for _, invalid := range []string{"123456", "abc:secret"} {
+		if _, err := ReadAccounts(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected error for %q, got none", invalid)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Attribute keys shared by every package, so log lines can be filtered consistently
+const (
+	KeyConnectionId  = "conn_id"
+	KeyRemoteIp      = "remote_ip"
+	KeyAccount       = "account"
+	KeyClientVersion = "client_version"
+	KeyError         = "error"
+)
+
+const redactedValue = "***"
+
+type Options struct {
+	Level          string
+	Format         string
+	RedactAccounts bool
+}
+
+// New creates a logger writing text or JSON lines to w
+func New(w io.Writer, options Options) (*slog.Logger, error) {
+	var level slog.Level
+	if options.Level != "" {
+		if err := level.UnmarshalText([]byte(options.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %s: %w", options.Level, err)
+		}
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	if options.RedactAccounts {
+		handlerOptions.ReplaceAttr = redactAccount
+	}
+
+	switch strings.ToLower(options.Format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, handlerOptions)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, handlerOptions)), nil
+	}
+
+	return nil, fmt.Errorf("invalid log format: %s", options.Format)
+}
+
+func redactAccount(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == KeyAccount {
+		return slog.String(KeyAccount, redactedValue)
+	}
+	return a
+}
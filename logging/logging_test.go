@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewJSONLogger(t *testing.T) {
+	var buffer bytes.Buffer
+
+	logger, err := New(&buffer, Options{Level: "debug", Format: "json"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	logger.Debug("login", KeyAccount, 123456, KeyRemoteIp, "10.0.0.1")
+
+	var line map[string]any
+	if err := json.Unmarshal(buffer.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", buffer.String(), err)
+	}
+
+	if line[KeyAccount] != float64(123456) {
+		t.Errorf("Expected account 123456, got %v", line[KeyAccount])
+	}
+
+	if line[KeyRemoteIp] != "10.0.0.1" {
+		t.Errorf("Expected remote ip 10.0.0.1, got %v", line[KeyRemoteIp])
+	}
+}
+
+func TestNewLoggerLevel(t *testing.T) {
+	var buffer bytes.Buffer
+
+	logger, err := New(&buffer, Options{Level: "warn"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+
+	if strings.Contains(buffer.String(), "hidden") {
+		t.Errorf("Expected info line to be filtered, got %q", buffer.String())
+	}
+
+	if !strings.Contains(buffer.String(), "shown") {
+		t.Errorf("Expected warn line to be written, got %q", buffer.String())
+	}
+}
+
+func TestNewLoggerRedactAccounts(t *testing.T) {
+	var buffer bytes.Buffer
+
+	logger, err := New(&buffer, Options{RedactAccounts: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	logger.With(KeyAccount, 123456).Info("login")
+
+	if strings.Contains(buffer.String(), "123456") {
+		t.Errorf("Expected account number to be redacted, got %q", buffer.String())
+	}
+}
+
+func TestNewLoggerInvalidOptions(t *testing.T) {
+	var buffer bytes.Buffer
+
+	if _, err := New(&buffer, Options{Level: "verbose"}); err == nil {
+		t.Error("Expected error for invalid level, got none")
+	}
+
+	if _, err := New(&buffer, Options{Format: "xml"}); err == nil {
+		t.Error("Expected error for invalid format, got none")
+	}
+}
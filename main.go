@@ -1,81 +1,463 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"go-opentibia-loginserver/admin"
+	"go-opentibia-loginserver/audit"
+	"go-opentibia-loginserver/balancer"
+	"go-opentibia-loginserver/banlist"
+	"go-opentibia-loginserver/cam"
 	"go-opentibia-loginserver/config"
 	"go-opentibia-loginserver/crypt"
 	"go-opentibia-loginserver/database"
+	"go-opentibia-loginserver/health"
+	"go-opentibia-loginserver/logging"
+	"go-opentibia-loginserver/maintenance"
+	"go-opentibia-loginserver/metrics"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/motd"
 	"go-opentibia-loginserver/packet"
 	"go-opentibia-loginserver/protocol"
 	"go-opentibia-loginserver/utils"
+	"log/slog"
 	"net"
 	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const Login uint8 = 0x01
 const PACKET_SIZE = 1024
 
+// lastLoginTimeout bounds the background write of the last login, so a stuck database does not pile up goroutines
+const lastLoginTimeout = 5 * time.Second
+
+// maxConcurrentRehashes limits the passwords rehashed in the background at once
+const maxConcurrentRehashes = 4
+
+// shutdownTimeout is how long the connections being handled are waited for on SIGINT and SIGTERM
+const shutdownTimeout = 10 * time.Second
+
+type LoginServer struct {
+	config           atomic.Pointer[config.Config]
+	db               *sql.DB
+	databaseQuery    database.DatabaseQuery
+	loginParser      *protocol.LoginParser
+	passwordScheme   string
+	passwordVerifier crypt.PasswordVerifier
+	loginAudit       *audit.Logger
+	banList          *banlist.BanList
+	maintenance      *maintenance.Mode
+	nextConnectionId atomic.Uint64
+	connections      sync.Map
+	loginStatsMutex  sync.Mutex
+	loginStats       map[string]uint64
+	reloadMutex      sync.Mutex
+	motdIds          *motd.IdStore
+	motdProvider     atomic.Pointer[motd.Provider]
+	worldHealth      *health.Checker
+	balancer         *balancer.Balancer
+	camProvider      cam.Provider
+	handlers         sync.WaitGroup
+	rehashSlots      chan struct{}
+	rehashing        sync.Map
+}
+
 func main() {
+	command, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "run":
+		os.Exit(runServer(args))
+	case "check-config":
+		os.Exit(runCheckConfig(args))
+	case "keygen":
+		os.Exit(runKeygen(args))
+	case "inspect":
+		os.Exit(runInspect(args))
+	case "login-test":
+		os.Exit(runLoginTest(args))
+	case "load-test":
+		os.Exit(runLoadTest(args))
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q, available commands are: run, check-config, keygen, inspect, login-test, load-test\n", command)
+	os.Exit(2)
+}
+
+func runServer(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	configFile := flags.String("config", "", "path to the config file (default: config.yaml in the search paths)")
+	flags.Parse(args)
 
-	config, err := config.LoadConfig()
+	config, err := config.LoadConfig(*configFile)
 	if err != nil {
-		fmt.Printf("Error loading config: %v", err)
+		slog.Error("error loading config", logging.KeyError, err)
+		return 1
 	}
 
-	rsaDecrypter, err := crypt.NewRSADecrypter(config.RSAKeyFile)
+	if err := validateConfig(&config); err != nil {
+		slog.Error("invalid config", logging.KeyError, err)
+		return 1
+	}
+
+	logger, err := logging.New(os.Stdout, logging.Options{Level: config.Log.Level, Format: config.Log.Format, RedactAccounts: config.Log.RedactAccounts})
 	if err != nil {
-		fmt.Println("Error loading private key:", err)
-		os.Exit(1)
+		slog.Error("error creating logger", logging.KeyError, err)
+		return 1
 	}
+	slog.SetDefault(logger)
 
-	databaseQuery := database.GetDatabaseQuery(config.QueryVersion)
-	if databaseQuery == nil {
-		fmt.Printf("unsupported database query version: %s\n", config.QueryVersion)
-		return
+	for _, warning := range configWarnings(&config) {
+		slog.Warn("suspicious config setting", "warning", warning)
+	}
+
+	keyRing, err := loadRSAKeys(&config)
+	if err != nil {
+		slog.Error("error loading private key", logging.KeyError, err)
+		return 1
 	}
+	keyRing.OnDecrypt = metrics.ObserveRSAKey
 
-	db, err := database.CreateDatabaseConnection(config.Database.User, config.Database.Password, config.Database.HostName, config.Database.Port, config.Database.Name)
+	databaseQuery, err := createDatabaseQuery(&config)
 	if err != nil {
-		fmt.Printf("error while creating database connection: %s\n", err)
+		slog.Error("error while creating database queries", logging.KeyError, err)
+		return 1
 	}
 
-	loginParser := protocol.NewLoginParser(rsaDecrypter)
+	passwordScheme := config.PasswordScheme
+	if passwordScheme == "" {
+		passwordScheme = databaseQuery.PasswordScheme()
+	}
+
+	passwordVerifier := crypt.GetPasswordVerifier(passwordScheme)
+	if passwordVerifier == nil {
+		slog.Error("unsupported password scheme", "scheme", passwordScheme)
+		return 1
+	}
+
+	if config.UpdateLastLogin {
+		if _, ok := databaseQuery.(database.LastLoginRecorder); !ok {
+			slog.Error("query version does not support updating the last login", "queryversion", config.QueryVersion)
+			return 1
+		}
+	}
+
+	if config.Cast.Enabled {
+		if _, ok := databaseQuery.(database.CastQuery); !ok {
+			slog.Error("query version does not support the cast system", "queryversion", config.QueryVersion)
+			return 1
+		}
+	}
+
+	var db *sql.DB
+	if config.QueryVersion != database.QueryVersionFile {
+		db, err = database.CreateDatabaseConnection(config.Database.User, config.Database.Password, config.Database.HostName, config.Database.Port, config.Database.Name)
+		if err != nil {
+			slog.Error("error while creating database connection", logging.KeyError, err)
+			return 1
+		}
+	}
+
+	loginAudit, err := createLoginAudit(&config, databaseQuery, db)
+	if err != nil {
+		slog.Error("error while creating login audit", logging.KeyError, err)
+		return 1
+	}
+	defer loginAudit.Close()
+
+	motdIds, err := motd.NewIdStore(config.MotdIdFile)
+	if err != nil {
+		slog.Error("error while loading MOTD ids", logging.KeyError, err)
+		return 1
+	}
+
+	motdProvider, err := createMotdProvider(&config, databaseQuery, db, motdIds)
+	if err != nil {
+		slog.Error("error while creating MOTD provider", logging.KeyError, err)
+		return 1
+	}
+
+	camProvider, err := createCamProvider(&config, databaseQuery, db)
+	if err != nil {
+		slog.Error("error while creating cam provider", logging.KeyError, err)
+		return 1
+	}
+
+	maintenanceSchedule, err := createMaintenanceSchedule(&config)
+	if err != nil {
+		slog.Error("error while creating maintenance schedule", logging.KeyError, err)
+		return 1
+	}
+
+	maintenanceMode := maintenance.New()
+	maintenanceMode.SetSchedule(maintenanceSchedule)
+	if err := maintenanceMode.Set(config.Maintenance.Enabled, config.Maintenance.BackAt); err != nil {
+		slog.Error("error while setting maintenance mode", logging.KeyError, err)
+		return 1
+	}
+
+	server := &LoginServer{
+		db:               db,
+		databaseQuery:    databaseQuery,
+		loginParser:      protocol.NewLoginParser(metrics.NewTimedDecrypter(keyRing)),
+		passwordScheme:   passwordScheme,
+		passwordVerifier: passwordVerifier,
+		loginAudit:       loginAudit,
+		banList:          banlist.New(),
+		maintenance:      maintenanceMode,
+		loginStats:       make(map[string]uint64),
+		motdIds:          motdIds,
+		camProvider:      camProvider,
+		rehashSlots:      make(chan struct{}, maxConcurrentRehashes),
+	}
+	server.config.Store(&config)
+	server.motdProvider.Store(motdProvider)
+
+	if config.WorldHealth.Enabled {
+		server.worldHealth = health.NewChecker(health.GetProbe(config.WorldHealth.Probe), config.WorldHealth.Interval, config.WorldHealth.Timeout, server.healthTargets)
+		server.worldHealth.Start()
+	}
+	server.balancer = balancer.New(server.worldHealth.IsOnline)
+
+	if config.Metrics.Enabled {
+		err = metrics.StartServer(config.Metrics.HostName, config.Metrics.Port)
+		if err != nil {
+			slog.Error("error while starting metrics server", logging.KeyError, err)
+			return 1
+		}
+	}
+
+	server.handleReloadSignals()
+	server.handleMaintenanceSignals()
+	if config.WatchConfig {
+		server.watchConfigFile()
+	}
+
+	if config.Admin.Enabled {
+		err = admin.NewServer(server, config.Admin.Token).Start(config.Admin.HostName, config.Admin.Port)
+		if err != nil {
+			slog.Error("error while starting admin server", logging.KeyError, err)
+			return 1
+		}
+	}
 
 	tcpListener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", config.LoginServer.HostName, config.LoginServer.Port))
 	if err != nil {
-		fmt.Println("Error:", err)
-		return
+		slog.Error("error while listening", logging.KeyError, err)
+		return 1
 	}
 	defer tcpListener.Close()
 
+	slog.Info("login server listening", "address", tcpListener.Addr().String())
+	handleShutdownSignals(tcpListener)
+
 	for {
 		tcpConnection, err := tcpListener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			break
+		}
 		if err != nil {
-			fmt.Println("Error:", err)
+			slog.Error("error while accepting connection", logging.KeyError, err)
 			continue
 		}
 
-		go handleTcpRequest(tcpConnection, loginParser, db, &config)
+		server.handlers.Add(1)
+		go func() {
+			defer server.handlers.Done()
+			server.handleTcpRequest(tcpConnection)
+		}()
+	}
+
+	server.waitForHandlers(shutdownTimeout)
+	slog.Info("login server stopped")
+	return 0
+}
+
+func loadRSAKeys(cfg *config.Config) (*crypt.KeyRing, error) {
+	var keys []*crypt.RSA
+
+	for _, configKey := range cfg.GetRSAKeys() {
+		passphrase, err := readPassphrase(configKey.Passphrase, configKey.PassphraseFile)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := crypt.NewRSADecrypterWithPassphrase(configKey.File, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+
+		slog.Info("loaded private key", "key", key.Name(), "bits", key.PublicKey().N.BitLen())
+	}
+
+	return crypt.NewKeyRing(keys...), nil
+}
+
+// readPassphrase returns passphrase, or the content of passphraseFile when it is set
+func readPassphrase(passphrase string, passphraseFile string) ([]byte, error) {
+	if passphraseFile == "" {
+		return []byte(passphrase), nil
+	}
+
+	data, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read passphrase file: %w", err)
+	}
+
+	return []byte(strings.TrimSpace(string(data))), nil
+}
+
+// createDatabaseQuery returns the queries of the configured schema version, or the accounts file store
+func createDatabaseQuery(cfg *config.Config) (database.DatabaseQuery, error) {
+	if cfg.QueryVersion == database.QueryVersionFile {
+		fileQuery, err := database.LoadFileQuery(cfg.AccountsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		slog.Warn("using the accounts file instead of a database, meant for development only", "file", cfg.AccountsFile)
+		return fileQuery, nil
+	}
+
+	databaseQuery := database.GetDatabaseQuery(cfg.QueryVersion)
+	if databaseQuery == nil {
+		return nil, fmt.Errorf("unsupported database query version: %s", cfg.QueryVersion)
+	}
+
+	return databaseQuery, nil
+}
+
+func createLoginAudit(cfg *config.Config, databaseQuery database.DatabaseQuery, db *sql.DB) (*audit.Logger, error) {
+	switch cfg.LoginAudit.Sink {
+	case "", "none":
+		return nil, nil
+	case "file":
+		sink, err := audit.NewFileSink(cfg.LoginAudit.File)
+		if err != nil {
+			return nil, err
+		}
+		return audit.NewLogger(sink, cfg.LoginAudit.QueueSize), nil
+	case "database":
+		recorder, ok := databaseQuery.(database.LoginAttemptRecorder)
+		if !ok {
+			return nil, fmt.Errorf("query version %s does not support storing login attempts", cfg.QueryVersion)
+		}
+		return audit.NewLogger(audit.NewDatabaseSink(recorder, db), cfg.LoginAudit.QueueSize), nil
+	}
+
+	return nil, fmt.Errorf("unsupported login audit sink: %s", cfg.LoginAudit.Sink)
+}
+
+func createMotdProvider(cfg *config.Config, databaseQuery database.DatabaseQuery, db *sql.DB, ids *motd.IdStore) (*motd.Provider, error) {
+	source, err := createMotdSource(cfg, databaseQuery, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules motd.Rules
+	if cfg.MotdRulesFile != "" {
+		rules, err = motd.LoadRules(cfg.MotdRulesFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return motd.NewProvider(source, rules, ids), nil
+}
+
+func createCamProvider(cfg *config.Config, databaseQuery database.DatabaseQuery, db *sql.DB) (cam.Provider, error) {
+	if !cfg.Cam.Enabled {
+		return nil, nil
 	}
 
+	switch cfg.Cam.Source {
+	case cam.SourceDirectory:
+		return cam.NewDirectoryProvider(cfg.Cam.Directory), nil
+	case cam.SourceDatabase:
+		query, ok := databaseQuery.(database.CamQuery)
+		if !ok {
+			return nil, fmt.Errorf("query version %s does not support the cam system", cfg.QueryVersion)
+		}
+		return cam.NewDatabaseProvider(query, db), nil
+	}
+
+	return nil, fmt.Errorf("unsupported cam source: %s", cfg.Cam.Source)
 }
 
-func handleTcpRequest(conn net.Conn, loginParser *protocol.LoginParser, db *sql.DB, cfg *config.Config) {
+func createMaintenanceSchedule(cfg *config.Config) ([]maintenance.Window, error) {
+	schedule := make([]maintenance.Window, 0, len(cfg.Maintenance.Schedule))
+
+	for _, configWindow := range cfg.Maintenance.Schedule {
+		window, err := maintenance.ParseWindow(configWindow.From, configWindow.Until)
+		if err != nil {
+			return nil, err
+		}
+		schedule = append(schedule, window)
+	}
+
+	return schedule, nil
+}
+
+func createMotdSource(cfg *config.Config, databaseQuery database.DatabaseQuery, db *sql.DB) (motd.Source, error) {
+	switch cfg.MotdSource {
+	case "", motd.SourceConfig:
+		return motd.NewConfigSource(cfg.Motd), nil
+	case motd.SourceFile:
+		return motd.NewFileSource(cfg.MotdFile), nil
+	case motd.SourceDatabase:
+		query, ok := databaseQuery.(database.MotdQuery)
+		if !ok {
+			return nil, fmt.Errorf("query version %s does not support reading the MOTD", cfg.QueryVersion)
+		}
+		return motd.NewDatabaseSource(query, db), nil
+	}
+
+	return nil, fmt.Errorf("unsupported MOTD source: %s", cfg.MotdSource)
+}
+
+func (s *LoginServer) handleTcpRequest(conn net.Conn) {
 	defer conn.Close()
 
+	metrics.ConnectionsAccepted.Inc()
+	metrics.ActiveConnections.Inc()
+	defer metrics.ActiveConnections.Dec()
+
+	connectionId := s.nextConnectionId.Add(1)
+	s.connections.Store(connectionId, models.Connection{Id: connectionId, RemoteAddress: conn.RemoteAddr().String(), ConnectedAt: time.Now().Unix()})
+	defer s.connections.Delete(connectionId)
+
+	logger := slog.With(logging.KeyConnectionId, connectionId, logging.KeyRemoteIp, conn.RemoteAddr().String())
+
 	packet := packet.NewIncoming(PACKET_SIZE)
 
 	reqLen, err := conn.Read(packet.PeekBuffer())
 	if err != nil {
-		fmt.Printf("[handleClient] - error reading: %s\n", err.Error())
+		logger.Warn("error reading", logging.KeyError, err)
 		return
 	}
 	packet.Resize(reqLen)
 
 	remoteIpAddress, err := utils.GetRemoteIpAddr(conn)
 	if err != nil {
-		fmt.Printf("[handleClient] - could not get remote IP address: %s\n", err)
+		logger.Warn("could not get remote IP address", logging.KeyError, err)
+		return
+	}
+
+	// the message size and the opcode
+	if packet.Remaining() < 3 {
+		logger.Warn("received packet too short for a message", "length", reqLen)
 		return
 	}
 
@@ -83,59 +465,246 @@ func handleTcpRequest(conn net.Conn, loginParser *protocol.LoginParser, db *sql.
 	clientOpcode := packet.GetUint8()
 
 	if clientOpcode == Login {
-		handleLoginRequest(conn, loginParser, db, cfg, packet, remoteIpAddress)
+		s.handleLoginRequest(conn, packet, remoteIpAddress, logger)
 	} else {
-		fmt.Printf("received invalid ClientOpCode (%d) from IP %d\n", clientOpcode, remoteIpAddress)
+		logger.Warn("received invalid client opcode", "opcode", clientOpcode)
 	}
 }
 
-func handleLoginRequest(conn net.Conn, loginParser *protocol.LoginParser, db *sql.DB, cfg *config.Config, packet *packet.Incoming, remoteIpAddress uint32) {
-	loginInfo, err := loginParser.ParseLogin(packet)
+func (s *LoginServer) handleLoginRequest(conn net.Conn, packet *packet.Incoming, remoteIpAddress uint32, logger *slog.Logger) {
+	cfg := s.config.Load()
+	loginInfo, err := s.loginParser.ParseLogin(packet)
+
+	outcome := models.LoginOutcomeInternalError
+	defer func() {
+		metrics.ObserveLogin(outcome)
+		s.recordLoginOutcome(outcome)
+		logger.Debug("login request handled", "outcome", outcome)
+		s.loginAudit.Log(models.LoginAttempt{
+			Timestamp:     time.Now().Unix(),
+			Ip:            remoteIpAddress,
+			AccountNumber: loginInfo.AccountNumber,
+			ClientVersion: loginInfo.ProtocolVersion,
+			ClientOs:      loginInfo.ClientOs,
+			Outcome:       outcome,
+		})
+	}()
+
 	if err != nil {
-		fmt.Printf("[handleClient] - error parsing login info: %s\n", err)
+		logger.Warn("error parsing login info", logging.KeyError, err)
+		outcome = models.LoginOutcomeParseError
 		return
 	}
 
-	databaseQuery := database.GetDatabaseQuery(cfg.QueryVersion)
+	logger = logger.With(logging.KeyAccount, loginInfo.AccountNumber, logging.KeyClientVersion, loginInfo.ProtocolVersion)
+	metrics.ObserveProtocolVersion(loginInfo.ProtocolVersion)
 
-	banInfo, err := databaseQuery.GetIpBanInfo(db, remoteIpAddress)
-	if err != nil {
-		fmt.Printf("[handleClient] - could not fetch ban info: %s\n", err)
-		return
+	banInfo := s.banList.GetIpBanInfo(remoteIpAddress)
+	if !banInfo.IsBanned {
+		start := time.Now()
+		banInfo, err = s.databaseQuery.GetIpBanInfo(s.db, remoteIpAddress)
+		metrics.ObservePhase(metrics.PhaseDbBan, start)
+		if err != nil {
+			logger.Error("could not fetch ban info", logging.KeyError, err)
+			outcome = models.LoginOutcomeDatabaseError
+			return
+		}
 	}
 
 	if banInfo.IsBanned {
 		banExpiresDateTime := utils.FormatDateTimeUTC(banInfo.ExpiresAt)
 		protocol.SendClientError(conn, loginInfo.XteaKey, fmt.Sprintf("Your IP has been banned until %s.\n\nReason specified:\n%s", banExpiresDateTime, banInfo.Reason))
+		outcome = models.LoginOutcomeBanned
+		return
+	}
+
+	if cfg.Cast.Enabled && loginInfo.AccountNumber == cfg.Cast.AccountNumber {
+		outcome = s.handleCastLogin(conn, &loginInfo, cfg, remoteIpAddress, logger)
+		return
+	}
+
+	if cfg.Cam.Enabled && loginInfo.AccountNumber == cfg.Cam.AccountNumber {
+		outcome = s.handleCamLogin(conn, &loginInfo, cfg, logger)
 		return
 	}
 
 	if loginInfo.AccountNumber == 0 {
 		protocol.SendClientError(conn, loginInfo.XteaKey, "Invalid account number.")
+		outcome = models.LoginOutcomeInvalidRequest
 		return
 	}
 
 	if loginInfo.Password == "" {
 		protocol.SendClientError(conn, loginInfo.XteaKey, "Invalid password.")
+		outcome = models.LoginOutcomeInvalidRequest
 		return
 	}
 
-	accountInfo, err := databaseQuery.GetAccountInfo(db, loginInfo.AccountNumber)
+	start := time.Now()
+	accountInfo, err := s.databaseQuery.GetAccountInfo(s.db, loginInfo.AccountNumber)
+	metrics.ObservePhase(metrics.PhaseDbAccount, start)
 	if err != nil {
-		fmt.Printf("[handleClient] - could not fetch account info: %s\n", err)
+		logger.Error("could not fetch account info", logging.KeyError, err)
+		outcome = models.LoginOutcomeDatabaseError
 		return
 	}
 
-	if utils.Sha1Hash(loginInfo.Password) != accountInfo.PasswordSHA1 {
+	// unknown accounts have no hash to verify
+	if accountInfo.Id == 0 || !s.passwordVerifier.Verify(loginInfo.Password, accountInfo.PasswordSalt, accountInfo.PasswordHash) {
+		if accountInfo.Id != 0 && s.passwordScheme == crypt.PasswordSchemeAuto && crypt.DetectPasswordScheme(accountInfo.PasswordHash) == crypt.PasswordSchemeUnknown {
+			logger.Warn("password hash of unknown scheme, login refused", "length", len(accountInfo.PasswordHash))
+		}
+
 		protocol.SendClientError(conn, loginInfo.XteaKey, "Account number of password is not correct.")
+		outcome = models.LoginOutcomeWrongPassword
+		return
+	}
+
+	if status := s.maintenance.Status(time.Now()); status.Active {
+		if !isStaffAccount(cfg, &accountInfo) {
+			protocol.SendClientError(conn, loginInfo.XteaKey, status.Message(cfg.Maintenance.Message))
+			outcome = models.LoginOutcomeMaintenance
+			return
+		}
+
+		logger.Info("staff account bypassed maintenance", "account_type", accountInfo.AccountType)
+	}
+
+	start = time.Now()
+	accountInfo.Characters, err = s.databaseQuery.GetCharactersList(s.db, accountInfo.Id)
+	metrics.ObservePhase(metrics.PhaseDbCharacters, start)
+	if err != nil {
+		logger.Error("could not fetch character list", logging.KeyError, err)
+		outcome = models.LoginOutcomeDatabaseError
+		return
+	}
+
+	//there is no support for multiworld yet, so get the default world
+	world, err := config.GetDefaultWorld(cfg)
+	if err != nil {
+		logger.Error("could not get world", logging.KeyError, err)
+		return
+	}
+
+	worldIp, worldPort, online := s.balancer.Select(&world, remoteIpAddress)
+	if !online {
+		if cfg.WorldHealth.OfflineAction != "hide" {
+			protocol.SendClientError(conn, loginInfo.XteaKey, fmt.Sprintf("World %s is currently offline, please try again later.", world.Name))
+			outcome = models.LoginOutcomeWorldOffline
+			return
+		}
+
+		accountInfo.Characters = nil
+	}
+
+	currentMotd, err := s.motdProvider.Load().Get(motd.Request{AccountType: accountInfo.AccountType, ClientVersion: loginInfo.ProtocolVersion})
+	if err != nil {
+		logger.Warn("could not get MOTD", logging.KeyError, err)
+	}
+
+	start = time.Now()
+	err = protocol.SendClientMotdAndCharacterList(conn, loginInfo.XteaKey, currentMotd, &accountInfo, &world, worldIp, worldPort)
+	metrics.ObservePhase(metrics.PhaseSend, start)
+	if err != nil {
+		logger.Warn("could not send character list", logging.KeyError, err)
+		outcome = models.LoginOutcomeSendError
+		return
+	}
+	outcome = models.LoginOutcomeOk
+
+	if cfg.UpdateLastLogin {
+		go s.updateLastLogin(accountInfo.Id, remoteIpAddress, logger)
+	}
+
+	if cfg.PasswordRehash.Enabled {
+		s.startPasswordRehash(cfg, accountInfo, loginInfo.Password, logger)
+	}
+}
+
+// healthTargets lists the gateways and address rules of every world in the current config, so reloaded worlds
+// are checked too
+func (s *LoginServer) healthTargets() []health.Target {
+	var targets []health.Target
+	seen := make(map[string]bool)
+
+	for _, world := range s.config.Load().GameServer.Worlds {
+		for _, gateway := range slices.Concat(world.GetGateways(), world.GetNetworkGateways()) {
+			if address := gateway.Address(); !seen[address] {
+				seen[address] = true
+				targets = append(targets, health.Target{World: world.Name, Address: address})
+			}
+		}
+	}
+
+	return targets
+}
+
+// isStaffAccount tells whether the account may login during maintenance
+func isStaffAccount(cfg *config.Config, accountInfo *models.AccountInfo) bool {
+	minAccountType := cfg.Maintenance.MinAccountType
+	if minAccountType == 0 {
+		minAccountType = maintenance.DefaultStaffAccountType
+	}
+
+	return accountInfo.AccountType >= minAccountType
+}
+
+func (s *LoginServer) updateLastLogin(accountId uint32, remoteIpAddress uint32, logger *slog.Logger) {
+	recorder := s.databaseQuery.(database.LastLoginRecorder)
+
+	ctx, cancel := context.WithTimeout(context.Background(), lastLoginTimeout)
+	defer cancel()
+
+	err := recorder.UpdateLastLogin(ctx, s.db, accountId, remoteIpAddress, time.Now().Unix())
+	if err != nil {
+		logger.Error("could not update last login", logging.KeyError, err)
+	}
+}
+
+// startPasswordRehash rehashes a legacy password in the background, as bcrypt and argon2 take long enough to delay
+// the login. It is skipped when maxConcurrentRehashes are running or the account is already being rehashed, the
+// next login of the account tries again.
+func (s *LoginServer) startPasswordRehash(cfg *config.Config, accountInfo models.AccountInfo, password string, logger *slog.Logger) {
+	currentScheme := crypt.DetectPasswordScheme(accountInfo.PasswordHash)
+	if currentScheme == cfg.PasswordRehash.Scheme {
+		return
+	}
+
+	if _, running := s.rehashing.LoadOrStore(accountInfo.Id, struct{}{}); running {
+		return
+	}
+
+	select {
+	case s.rehashSlots <- struct{}{}:
+	default:
+		s.rehashing.Delete(accountInfo.Id)
+		logger.Debug("too many password rehashes running, skipping it until the next login")
+		return
+	}
+
+	go func() {
+		defer func() {
+			<-s.rehashSlots
+			s.rehashing.Delete(accountInfo.Id)
+		}()
+
+		s.rehashAccountPassword(cfg, &accountInfo, currentScheme, password, logger)
+	}()
+}
+
+// rehashAccountPassword migrates a legacy password hash to the configured scheme, once the client proved it knows the password
+func (s *LoginServer) rehashAccountPassword(cfg *config.Config, accountInfo *models.AccountInfo, currentScheme string, password string, logger *slog.Logger) {
+	passwordHash, err := crypt.GetPasswordHasher(cfg.PasswordRehash.Scheme).Hash(password)
+	if err != nil {
+		logger.Error("could not hash password", logging.KeyError, err)
 		return
 	}
 
-	accountInfo.Characters, err = databaseQuery.GetCharactersList(db, accountInfo.Id)
+	err = s.databaseQuery.UpdateAccountPassword(s.db, accountInfo.Id, passwordHash)
 	if err != nil {
-		fmt.Printf("[handleClient] - could not fetch character list: %s\n", err)
+		logger.Error("could not update password", logging.KeyError, err)
 		return
 	}
 
-	protocol.SendClientMotdAndCharacterList(conn, loginInfo.XteaKey, cfg.Motd, &accountInfo, cfg)
+	logger.Info("rehashed account password", "from", currentScheme, "to", cfg.PasswordRehash.Scheme)
 }
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"crypto/rsa"
+	"database/sql"
+	"fmt"
+	"go-opentibia-loginserver/balancer"
+	"go-opentibia-loginserver/banlist"
+	"go-opentibia-loginserver/cam"
+	"go-opentibia-loginserver/client"
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/crypt"
+	"go-opentibia-loginserver/database"
+	"go-opentibia-loginserver/maintenance"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/motd"
+	"go-opentibia-loginserver/packet"
+	"go-opentibia-loginserver/protocol"
+	"go-opentibia-loginserver/utils"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testClientIp    = 0x0100000A                                 // 10.0.0.1
+	testBannedIp    = 0x0200000A                                 // 10.0.0.2
+	testPasswordSHA = "e5e9fa1ba31ecd1ae84f75caaa474f3a663f05f4" // sha1 of "secret"
+)
+
+// newTestServer builds a login server backed by an accounts file store, with no database and no listener
+func newTestServer(t *testing.T) (*LoginServer, *rsa.PublicKey) {
+	privateKey, err := crypt.GenerateRSAKey(1024)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+	key := crypt.NewRSADecrypterFromKey("key.pem", privateKey)
+
+	databaseQuery, err := database.NewFileQuery(database.FileStore{
+		Accounts: []database.FileAccount{
+			{Number: 123456, Password: testPasswordSHA, Type: 1, Characters: []database.FileCharacter{{Name: "Knight"}, {Name: "Druid"}}},
+			{Number: 777777, Password: testPasswordSHA, Type: 5, Characters: []database.FileCharacter{{Name: "GM Test"}}},
+		},
+		IpBans: []database.FileIpBan{{Ip: "10.0.0.2", Reason: "botting", BannedBy: "GM"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create file store: %v", err)
+	}
+
+	motdIds, _ := motd.NewIdStore("")
+	worldIp, _ := utils.IpToUint32("127.0.0.1")
+
+	server := &LoginServer{
+		databaseQuery:    databaseQuery,
+		loginParser:      protocol.NewLoginParser(crypt.NewKeyRing(key)),
+		passwordScheme:   databaseQuery.PasswordScheme(),
+		passwordVerifier: crypt.GetPasswordVerifier(databaseQuery.PasswordScheme()),
+		banList:          banlist.New(),
+		maintenance:      maintenance.New(),
+		loginStats:       make(map[string]uint64),
+		motdIds:          motdIds,
+		rehashSlots:      make(chan struct{}, maxConcurrentRehashes),
+	}
+	server.config.Store(&config.Config{
+		GameServer:   config.GameServer{Worlds: []config.World{{Name: "Test", HostName: "127.0.0.1", Port: 7172, HostIP: worldIp}}},
+		QueryVersion: database.QueryVersionFile,
+	})
+	server.motdProvider.Store(motd.NewProvider(motd.NewConfigSource("Welcome!"), nil, motdIds))
+	server.balancer = balancer.New(server.worldHealth.IsOnline)
+
+	return server, key.PublicKey()
+}
+
+// login sends a login to handleLoginRequest through an in-memory connection and reads the answer
+func login(t *testing.T, server *LoginServer, publicKey *rsa.PublicKey, remoteIp uint32, accountNumber uint32, password string) *client.Response {
+	xteaKey := [4]uint32{1, 2, 3, 4}
+
+	data, err := client.BuildLoginPacket(publicKey, protocol.LoginRequest{
+		ClientOs:        client.DefaultClientOs,
+		ProtocolVersion: client.DefaultProtocolVersion,
+		XteaKey:         xteaKey,
+		AccountNumber:   accountNumber,
+		Password:        password,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build login packet: %v", err)
+	}
+
+	incoming := packet.NewIncoming(PACKET_SIZE)
+	copy(incoming.PeekBuffer(), data)
+	incoming.Resize(len(data))
+	incoming.GetUint16() // message size
+	incoming.GetUint8()  // opcode
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer serverConn.Close()
+		server.handleLoginRequest(serverConn, incoming, remoteIp, slog.Default())
+	}()
+
+	clientConn.SetDeadline(time.Now().Add(5 * time.Second))
+	response, err := client.ReadResponse(clientConn, xteaKey)
+	<-done
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	return response
+}
+
+func TestHandleLoginRequest(t *testing.T) {
+	server, publicKey := newTestServer(t)
+
+	response := login(t, server, publicKey, testClientIp, 123456, "secret")
+	if response.Error != "" {
+		t.Fatalf("Expected a character list, got error %q", response.Error)
+	}
+
+	if response.Motd != "Welcome!" || response.MotdId == 0 {
+		t.Errorf("Expected the configured MOTD, got %d %q", response.MotdId, response.Motd)
+	}
+
+	if len(response.Characters) != 2 || response.Characters[0].Name != "Druid" || response.Characters[1].Name != "Knight" {
+		t.Fatalf("Unexpected characters: %+v", response.Characters)
+	}
+
+	if character := response.Characters[0]; character.World != "Test" || character.Address() != "127.0.0.1:7172" {
+		t.Errorf("Expected characters on world Test at 127.0.0.1:7172, got %+v", character)
+	}
+
+	if stats := server.loginStats[models.LoginOutcomeOk]; stats != 1 {
+		t.Errorf("Expected 1 successful login in the stats, got %d", stats)
+	}
+}
+
+func TestHandleLoginRequestRefused(t *testing.T) {
+	server, publicKey := newTestServer(t)
+
+	tests := []struct {
+		name          string
+		remoteIp      uint32
+		accountNumber uint32
+		password      string
+		expected      string
+		outcome       string
+	}{
+		{"wrong password", testClientIp, 123456, "wrong", "password is not correct", models.LoginOutcomeWrongPassword},
+		{"unknown account", testClientIp, 654321, "secret", "password is not correct", models.LoginOutcomeWrongPassword},
+		{"no account number", testClientIp, 0, "secret", "Invalid account number.", models.LoginOutcomeInvalidRequest},
+		{"no password", testClientIp, 123456, "", "Invalid password.", models.LoginOutcomeInvalidRequest},
+		{"banned ip", testBannedIp, 123456, "secret", "Reason specified:\nbotting", models.LoginOutcomeBanned},
+	}
+
+	for _, test := range tests {
+		response := login(t, server, publicKey, test.remoteIp, test.accountNumber, test.password)
+
+		if !strings.Contains(response.Error, test.expected) {
+			t.Errorf("%s: expected error containing %q, got %+v", test.name, test.expected, response)
+		}
+
+		if server.loginStats[test.outcome] == 0 {
+			t.Errorf("%s: expected outcome %s in the stats, got %v", test.name, test.outcome, server.loginStats)
+		}
+	}
+}
+
+func TestHandleLoginRequestMaintenance(t *testing.T) {
+	server, publicKey := newTestServer(t)
+	server.maintenance.Set(true, "14:30")
+
+	response := login(t, server, publicKey, testClientIp, 123456, "secret")
+	if response.Error != "Server is under maintenance, back at 14:30." {
+		t.Errorf("Expected the maintenance error, got %+v", response)
+	}
+
+	response = login(t, server, publicKey, testClientIp, 777777, "secret")
+	if response.Error != "" || len(response.Characters) != 1 {
+		t.Errorf("Expected the staff account to bypass maintenance, got %+v", response)
+	}
+}
+
+func TestHandleTcpRequestShortPacket(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	for _, size := range []int{0, 1, 18} {
+		clientConn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+
+		serverConn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Failed to accept: %v", err)
+		}
+
+		data := make([]byte, size)
+		if size > 2 {
+			data[2] = Login
+		}
+		clientConn.Write(data)
+		clientConn.Close()
+
+		// a short packet must be dropped, not panic
+		server.handleTcpRequest(serverConn)
+	}
+
+	if stats := server.loginStats[models.LoginOutcomeParseError]; stats != 1 {
+		t.Errorf("Expected the 18-byte login to be a parse error, got %v", server.loginStats)
+	}
+}
+
+// enablePasswordRehash turns on the migration of legacy hashes to bcrypt
+func enablePasswordRehash(server *LoginServer) {
+	cfg := *server.config.Load()
+	cfg.PasswordRehash = config.PasswordRehash{Enabled: true, Scheme: crypt.PasswordSchemeBcrypt}
+	server.config.Store(&cfg)
+}
+
+func passwordHash(t *testing.T, server *LoginServer, accountNumber uint32) string {
+	accountInfo, err := server.databaseQuery.GetAccountInfo(nil, accountNumber)
+	if err != nil {
+		t.Fatalf("Failed to get account info: %v", err)
+	}
+	return accountInfo.PasswordHash
+}
+
+func TestPasswordRehash(t *testing.T) {
+	server, publicKey := newTestServer(t)
+	enablePasswordRehash(server)
+
+	if response := login(t, server, publicKey, testClientIp, 123456, "secret"); response.Error != "" {
+		t.Fatalf("Expected a character list, got error %q", response.Error)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for crypt.DetectPasswordScheme(passwordHash(t, server, 123456)) != crypt.PasswordSchemeBcrypt {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the password to be rehashed with bcrypt")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if response := login(t, server, publicKey, testClientIp, 123456, "secret"); response.Error != "" {
+		t.Errorf("Expected the rehashed password to be accepted, got error %q", response.Error)
+	}
+}
+
+func TestPasswordRehashSkipped(t *testing.T) {
+	server, publicKey := newTestServer(t)
+	enablePasswordRehash(server)
+
+	// 123456 is already being rehashed, and no slot is left for 777777
+	server.rehashing.Store(uint32(123456), struct{}{})
+	for i := 0; i < maxConcurrentRehashes; i++ {
+		server.rehashSlots <- struct{}{}
+	}
+
+	login(t, server, publicKey, testClientIp, 123456, "secret")
+	login(t, server, publicKey, testClientIp, 777777, "secret")
+	time.Sleep(100 * time.Millisecond)
+
+	for _, accountNumber := range []uint32{123456, 777777} {
+		if hash := passwordHash(t, server, accountNumber); hash != testPasswordSHA {
+			t.Errorf("Expected account %d not to be rehashed, got %s", accountNumber, hash)
+		}
+	}
+}
+
+func TestUpdateLastLogin(t *testing.T) {
+	server, publicKey := newTestServer(t)
+	cfg := *server.config.Load()
+	cfg.UpdateLastLogin = true
+	server.config.Store(&cfg)
+	fileQuery := server.databaseQuery.(*database.FileQuery)
+
+	login(t, server, publicKey, testClientIp, 777777, "wrong")
+	login(t, server, publicKey, testClientIp, 123456, "secret")
+
+	deadline := time.Now().Add(5 * time.Second)
+	lastLogin, found := fileQuery.LastLogin(123456)
+	for !found && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		lastLogin, found = fileQuery.LastLogin(123456)
+	}
+
+	if !found || lastLogin.Ip != testClientIp || time.Since(time.Unix(lastLogin.Timestamp, 0)) > time.Minute {
+		t.Errorf("Expected the login from 10.0.0.1 to be recorded, got %+v", lastLogin)
+	}
+
+	if lastLogin, found := fileQuery.LastLogin(777777); found {
+		t.Errorf("Expected the refused login not to be recorded, got %+v", lastLogin)
+	}
+}
+
+// castFileQuery adds live casts to the accounts file store, which has none
+type castFileQuery struct {
+	*database.FileQuery
+	casts []models.LiveCast
+}
+
+func (q castFileQuery) GetLiveCasts(*sql.DB) ([]models.LiveCast, error) {
+	return q.casts, nil
+}
+
+func TestHandleCastLogin(t *testing.T) {
+	server, publicKey := newTestServer(t)
+	server.databaseQuery = castFileQuery{
+		FileQuery: server.databaseQuery.(*database.FileQuery),
+		casts: []models.LiveCast{
+			{PlayerName: "Protected", Password: "letmein", Spectators: 12},
+			{PlayerName: "Public", Spectators: 3},
+		},
+	}
+	cfg := *server.config.Load()
+	cfg.Cast = config.Cast{Enabled: true, AccountNumber: 888888}
+	server.config.Store(&cfg)
+
+	tests := []struct {
+		name     string
+		password string
+		expected []string
+	}{
+		{"no password", "", []string{"Public"}},
+		{"wrong password", "wrong", []string{"Public"}},
+		{"cast password", "letmein", []string{"Protected", "Public"}},
+	}
+
+	for _, test := range tests {
+		response := login(t, server, publicKey, testClientIp, 888888, test.password)
+		if response.Error != "" {
+			t.Fatalf("%s: expected a cast list, got error %q", test.name, response.Error)
+		}
+
+		var names []string
+		for _, character := range response.Characters {
+			names = append(names, character.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%s: expected casts %v, got %v", test.name, test.expected, names)
+		}
+
+		if character := response.Characters[0]; character.World != "Test" || character.Address() != "127.0.0.1:7172" {
+			t.Errorf("%s: expected casts on world Test at 127.0.0.1:7172, got %+v", test.name, character)
+		}
+	}
+}
+
+// camRecordings is a cam provider answering a fixed list of recordings
+type camRecordings []models.CamRecording
+
+func (r camRecordings) GetRecordings() ([]models.CamRecording, error) {
+	return r, nil
+}
+
+func TestHandleCamLogin(t *testing.T) {
+	server, publicKey := newTestServer(t)
+
+	recordings := make(camRecordings, 40)
+	for i := range recordings {
+		recordings[i] = models.CamRecording{
+			Id:         uint32(i + 1),
+			PlayerName: fmt.Sprintf("A Rather Long Player Name %d", i+1),
+			RecordedAt: 1700000000 + int64(i)*60,
+			Duration:   3600,
+		}
+	}
+	server.camProvider = recordings
+
+	cfg := *server.config.Load()
+	cfg.Cam = config.Cam{Enabled: true, AccountNumber: 999999, HostName: "127.0.0.1", Port: 7173}
+	server.config.Store(&cfg)
+
+	response := login(t, server, publicKey, testClientIp, 999999, "anything")
+	if response.Error != "" {
+		t.Fatalf("Expected a cam list, got error %q", response.Error)
+	}
+
+	entries := cam.Entries(recordings, 0)
+	fitting := protocol.FitCharacters(models.Motd{}, entries, cam.DefaultWorldName)
+	if fitting >= len(recordings) {
+		t.Fatalf("Expected the recordings not to fit in one packet, %d of %d fit", fitting, len(recordings))
+	}
+
+	if len(response.Characters) != fitting {
+		t.Fatalf("Expected the list trimmed to %d cams, got %d", fitting, len(response.Characters))
+	}
+
+	if response.Characters[0].Name != cam.EntryName(recordings[len(recordings)-1]) {
+		t.Errorf("Expected the latest recording first, got %q", response.Characters[0].Name)
+	}
+
+	if character := response.Characters[0]; character.World != cam.DefaultWorldName || character.Address() != "127.0.0.1:7173" {
+		t.Errorf("Expected cams on world %s at 127.0.0.1:7173, got %+v", cam.DefaultWorldName, character)
+	}
+}
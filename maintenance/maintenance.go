@@ -0,0 +1,150 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMessage       = "Server is under maintenance, please try again later."
+	DefaultBackAtMessage = "Server is under maintenance, back at {backat}."
+
+	// DefaultStaffAccountType is the lowest account type (gamemaster) allowed to login during maintenance
+	DefaultStaffAccountType uint32 = 4
+
+	backAtPlaceholder = "{backat}"
+)
+
+// Clock is a time of day in minutes since midnight, written as HH:MM
+type Clock int
+
+func ParseClock(value string) (Clock, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+
+	return Clock(parsed.Hour()*60 + parsed.Minute()), nil
+}
+
+func ClockOf(t time.Time) Clock {
+	return Clock(t.Hour()*60 + t.Minute())
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c/60, c%60)
+}
+
+// Window is a daily maintenance period, such as the server save. A window ending before it starts spans midnight.
+type Window struct {
+	From  Clock
+	Until Clock
+}
+
+func ParseWindow(from string, until string) (Window, error) {
+	var window Window
+	var err error
+
+	if window.From, err = ParseClock(from); err != nil {
+		return window, err
+	}
+
+	if window.Until, err = ParseClock(until); err != nil {
+		return window, err
+	}
+
+	// an empty window would never be active, a full day is better written as maintenance.enabled
+	if window.From == window.Until {
+		return window, fmt.Errorf("window from %s until %s is empty", from, until)
+	}
+
+	return window, nil
+}
+
+func (w Window) Contains(clock Clock) bool {
+	if w.From <= w.Until {
+		return clock >= w.From && clock < w.Until
+	}
+
+	return clock >= w.From || clock < w.Until
+}
+
+// Status is the maintenance state at a given time, BackAt is empty when the end is unknown
+type Status struct {
+	Active    bool
+	Scheduled bool
+	BackAt    string
+}
+
+// Message formats the error sent to players, replacing {backat} in template
+func (s Status) Message(template string) string {
+	if s.BackAt == "" {
+		if template == "" || strings.Contains(template, backAtPlaceholder) {
+			return DefaultMessage
+		}
+		return template
+	}
+
+	if template == "" {
+		template = DefaultBackAtMessage
+	}
+
+	return strings.ReplaceAll(template, backAtPlaceholder, s.BackAt)
+}
+
+// Mode combines the maintenance switch, toggled by config, signals or the admin API, with the daily schedule
+type Mode struct {
+	mu       sync.Mutex
+	enabled  bool
+	backAt   string
+	schedule []Window
+}
+
+func New() *Mode {
+	return &Mode{}
+}
+
+// Set turns maintenance on or off, backAt (HH:MM, optional) is shown to players until it is turned off
+func (m *Mode) Set(enabled bool, backAt string) error {
+	if backAt != "" {
+		clock, err := ParseClock(backAt)
+		if err != nil {
+			return err
+		}
+		backAt = clock.String()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.enabled = enabled
+	m.backAt = backAt
+	return nil
+}
+
+func (m *Mode) SetSchedule(schedule []Window) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.schedule = schedule
+}
+
+func (m *Mode) Status(now time.Time) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.enabled {
+		return Status{Active: true, BackAt: m.backAt}
+	}
+
+	clock := ClockOf(now)
+	for _, window := range m.schedule {
+		if window.Contains(clock) {
+			return Status{Active: true, Scheduled: true, BackAt: window.Until.String()}
+		}
+	}
+
+	return Status{}
+}
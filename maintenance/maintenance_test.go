@@ -0,0 +1,117 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func at(hour int, minute int) time.Time {
+	return time.Date(2024, time.March, 4, hour, minute, 0, 0, time.Local)
+}
+
+func TestParseClock(t *testing.T) {
+	clock, err := ParseClock("6:05")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if clock.String() != "06:05" {
+		t.Errorf("Expected 06:05, got %s", clock)
+	}
+
+	for _, value := range []string{"", "24:00", "12:60", "noon"} {
+		if _, err := ParseClock(value); err == nil {
+			t.Errorf("Expected an error for %q, got none", value)
+		}
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	window, err := ParseWindow("23:30", "00:30")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if window.From.String() != "23:30" || window.Until.String() != "00:30" {
+		t.Errorf("Expected 23:30 until 00:30, got %s until %s", window.From, window.Until)
+	}
+
+	for _, value := range [][2]string{{"06:00", "06:00"}, {"6:00", "06:00"}, {"06:00", "6h"}, {"noon", "06:00"}} {
+		if _, err := ParseWindow(value[0], value[1]); err == nil {
+			t.Errorf("Expected an error for %s until %s, got none", value[0], value[1])
+		}
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	saveWindow, _ := ParseWindow("05:50", "06:10")
+	nightWindow, _ := ParseWindow("23:30", "00:30")
+
+	tests := []struct {
+		window   Window
+		now      time.Time
+		expected bool
+	}{
+		{saveWindow, at(5, 49), false},
+		{saveWindow, at(5, 50), true},
+		{saveWindow, at(6, 9), true},
+		{saveWindow, at(6, 10), false},
+		{nightWindow, at(23, 45), true},
+		{nightWindow, at(0, 15), true},
+		{nightWindow, at(12, 0), false},
+	}
+
+	for _, test := range tests {
+		if result := test.window.Contains(ClockOf(test.now)); result != test.expected {
+			t.Errorf("Expected %v for %s-%s at %s, got %v", test.expected, test.window.From, test.window.Until, test.now.Format("15:04"), result)
+		}
+	}
+}
+
+func TestModeStatus(t *testing.T) {
+	mode := New()
+	saveWindow, _ := ParseWindow("05:50", "06:10")
+	mode.SetSchedule([]Window{saveWindow})
+
+	if status := mode.Status(at(12, 0)); status.Active {
+		t.Errorf("Expected maintenance to be inactive, got %+v", status)
+	}
+
+	status := mode.Status(at(6, 0))
+	if !status.Active || !status.Scheduled || status.BackAt != "06:10" {
+		t.Errorf("Expected scheduled maintenance until 06:10, got %+v", status)
+	}
+
+	if err := mode.Set(true, "14:30"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	status = mode.Status(at(12, 0))
+	if !status.Active || status.Scheduled || status.BackAt != "14:30" {
+		t.Errorf("Expected maintenance until 14:30, got %+v", status)
+	}
+
+	if err := mode.Set(true, "later"); err == nil {
+		t.Error("Expected an error for an invalid back at time, got none")
+	}
+}
+
+func TestStatusMessage(t *testing.T) {
+	tests := []struct {
+		status   Status
+		template string
+		expected string
+	}{
+		{Status{Active: true}, "", DefaultMessage},
+		{Status{Active: true, BackAt: "06:10"}, "", "Server is under maintenance, back at 06:10."},
+		{Status{Active: true, BackAt: "06:10"}, "Server save, back at {backat}!", "Server save, back at 06:10!"},
+		{Status{Active: true}, "Server save, back at {backat}!", DefaultMessage},
+		{Status{Active: true}, "Server save in progress.", "Server save in progress."},
+	}
+
+	for _, test := range tests {
+		if message := test.status.Message(test.template); message != test.expected {
+			t.Errorf("Expected %q, got %q", test.expected, message)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"go-opentibia-loginserver/crypt"
+	"time"
+)
+
+// TimedDecrypter records the RSA decryption time of the wrapped decrypter
+type TimedDecrypter struct {
+	decrypter crypt.Decrypter
+}
+
+func NewTimedDecrypter(decrypter crypt.Decrypter) *TimedDecrypter {
+	return &TimedDecrypter{decrypter: decrypter}
+}
+
+func (d *TimedDecrypter) DecryptNoPadding(ciphertext []byte) ([]byte, error) {
+	defer ObservePhase(PhaseRSADecrypt, time.Now())
+	return d.decrypter.DecryptNoPadding(ciphertext)
+}
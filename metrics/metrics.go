@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "opentibia_login"
+
+const (
+	PhaseRSADecrypt   = "rsa_decrypt"
+	PhaseDbBan        = "db_ban"
+	PhaseDbAccount    = "db_account"
+	PhaseDbCharacters = "db_characters"
+	PhaseDbCasts      = "db_casts"
+	PhaseCams         = "cams"
+	PhaseSend         = "send"
+)
+
+var (
+	ConnectionsAccepted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connections_accepted_total",
+		Help:      "Number of TCP connections accepted.",
+	})
+
+	ActiveConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_connections",
+		Help:      "Number of TCP connections currently being handled.",
+	})
+
+	logins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of login requests by outcome.",
+	}, []string{"outcome"})
+
+	phaseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "phase_duration_seconds",
+		Help:      "Time spent on each phase of a login request.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"phase"})
+
+	protocolVersions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "protocol_versions_total",
+		Help:      "Number of parsed login requests by client protocol version.",
+	}, []string{"version"})
+
+	rsaKeyDecryptions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rsa_key_decryptions_total",
+		Help:      "Number of login blocks decrypted by each RSA key.",
+	}, []string{"key"})
+
+	worldOnline = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "world_online",
+		Help:      "Whether the world address answered the last health check (1) or not (0).",
+	}, []string{"world", "address"})
+)
+
+func ObserveLogin(outcome string) {
+	logins.WithLabelValues(outcome).Inc()
+}
+
+// ObservePhase records the time elapsed since start, meant to be deferred or called right after the phase
+func ObservePhase(phase string, start time.Time) {
+	phaseDuration.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+}
+
+func ObserveProtocolVersion(version uint16) {
+	protocolVersions.WithLabelValues(strconv.Itoa(int(version))).Inc()
+}
+
+func ObserveRSAKey(keyName string) {
+	rsaKeyDecryptions.WithLabelValues(keyName).Inc()
+}
+
+func ObserveWorldOnline(world string, address string, online bool) {
+	value := 0.0
+	if online {
+		value = 1
+	}
+	worldOnline.WithLabelValues(world, address).Set(value)
+}
+
+// StartServer serves the metrics on /metrics in the background
+func StartServer(hostname string, port int) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", hostname, port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics server stopped", "error", err)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestObserveLogin(t *testing.T) {
+	before := testutil.ToFloat64(logins.WithLabelValues("internal_error"))
+
+	ObserveLogin("internal_error")
+	ObserveLogin("internal_error")
+	ObserveLogin("ok")
+
+	if count := testutil.ToFloat64(logins.WithLabelValues("internal_error")) - before; count != 2 {
+		t.Errorf("Expected 2 internal_error logins, got %v", count)
+	}
+}
+
+func TestObservePhase(t *testing.T) {
+	histogram := phaseDuration.WithLabelValues(PhaseDbAccount).(prometheus.Histogram)
+
+	var before dto.Metric
+	if err := histogram.Write(&before); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	ObservePhase(PhaseDbAccount, time.Now().Add(-20*time.Millisecond))
+
+	var after dto.Metric
+	if err := histogram.Write(&after); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if count := after.GetHistogram().GetSampleCount() - before.GetHistogram().GetSampleCount(); count != 1 {
+		t.Errorf("Expected 1 observation, got %d", count)
+	}
+
+	if elapsed := after.GetHistogram().GetSampleSum() - before.GetHistogram().GetSampleSum(); elapsed < 0.02 {
+		t.Errorf("Expected at least 20ms to be observed, got %vs", elapsed)
+	}
+}
+
+func TestObserveWorldOnline(t *testing.T) {
+	ObserveWorldOnline("Test", "127.0.0.1:7172", true)
+	if value := testutil.ToFloat64(worldOnline.WithLabelValues("Test", "127.0.0.1:7172")); value != 1 {
+		t.Errorf("Expected the world to be online, got %v", value)
+	}
+
+	ObserveWorldOnline("Test", "127.0.0.1:7172", false)
+	if value := testutil.ToFloat64(worldOnline.WithLabelValues("Test", "127.0.0.1:7172")); value != 0 {
+		t.Errorf("Expected the world to be offline, got %v", value)
+	}
+}
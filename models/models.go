@@ -9,8 +9,58 @@ type BanInfo struct {
 
 type AccountInfo struct {
 	Id            uint32
-	PasswordSHA1  string
+	PasswordHash  string
+	PasswordSalt  string
 	AccountType   uint32
 	PremiumEndsAt int64
 	Characters    []string
 }
+
+const (
+	LoginOutcomeOk             = "ok"
+	LoginOutcomeBanned         = "banned"
+	LoginOutcomeWrongPassword  = "wrong_password"
+	LoginOutcomeInvalidRequest = "invalid_request"
+	LoginOutcomeParseError     = "parse_error"
+	LoginOutcomeDatabaseError  = "database_error"
+	LoginOutcomeInternalError  = "internal_error"
+	LoginOutcomeMaintenance    = "maintenance"
+	LoginOutcomeWorldOffline   = "world_offline"
+	LoginOutcomeEmptyList      = "empty_list"
+	LoginOutcomeSendError      = "send_error"
+)
+
+type LoginAttempt struct {
+	Timestamp     int64
+	Ip            uint32
+	AccountNumber uint32
+	ClientVersion uint16
+	ClientOs      uint16
+	Outcome       string
+}
+
+// LiveCast is a character currently broadcasting its game, Password is empty for public casts
+type LiveCast struct {
+	PlayerName string
+	Password   string
+	Spectators uint32
+}
+
+// CamRecording is a recorded game session, available to be watched on the cam playback server
+type CamRecording struct {
+	Id         uint32
+	PlayerName string
+	RecordedAt int64
+	Duration   uint32
+}
+
+type Connection struct {
+	Id            uint64
+	RemoteAddress string
+	ConnectedAt   int64
+}
+
+type Motd struct {
+	Id   uint32
+	Text string
+}
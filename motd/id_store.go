@@ -0,0 +1,103 @@
+package motd
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// firstId is above 1 because older versions always sent the MOTD with id 1, which clients already acknowledged
+const firstId = 2
+
+// IdStore gives every distinct MOTD text its own id, so clients show each new MOTD once.
+// Ids are kept in a JSON file, when a filename is given, to survive restarts.
+type IdStore struct {
+	mu       sync.Mutex
+	filename string
+	state    idStoreState
+}
+
+type idStoreState struct {
+	LastId uint32            `json:"last_id"`
+	Ids    map[string]uint32 `json:"ids"`
+}
+
+func NewIdStore(filename string) (*IdStore, error) {
+	store := &IdStore{
+		filename: filename,
+		state:    idStoreState{LastId: firstId - 1, Ids: make(map[string]uint32)},
+	}
+
+	if filename == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MOTD id file %s: %w", filename, err)
+	}
+
+	if err := json.Unmarshal(data, &store.state); err != nil {
+		return nil, fmt.Errorf("failed to decode MOTD id file %s: %w", filename, err)
+	}
+
+	if store.state.Ids == nil {
+		store.state.Ids = make(map[string]uint32)
+	}
+
+	return store, nil
+}
+
+// IdFor returns the id of a MOTD text, assigning and persisting the next id when the text is new
+func (s *IdStore) IdFor(text string) (uint32, error) {
+	hash := sha1.Sum([]byte(text))
+	key := hex.EncodeToString(hash[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, found := s.state.Ids[key]; found {
+		return id, nil
+	}
+
+	s.state.LastId++
+	s.state.Ids[key] = s.state.LastId
+
+	if err := s.save(); err != nil {
+		return s.state.LastId, err
+	}
+
+	return s.state.LastId, nil
+}
+
+func (s *IdStore) save() error {
+	if s.filename == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode MOTD ids: %w", err)
+	}
+
+	// write to a temporary file first, so a crash never leaves a truncated file behind
+	temporaryFile := filepath.Join(filepath.Dir(s.filename), "."+filepath.Base(s.filename)+".tmp")
+	if err := os.WriteFile(temporaryFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write MOTD id file: %w", err)
+	}
+
+	if err := os.Rename(temporaryFile, s.filename); err != nil {
+		return fmt.Errorf("failed to replace MOTD id file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package motd
+
+import (
+	"database/sql"
+	"fmt"
+	"go-opentibia-loginserver/database"
+	"go-opentibia-loginserver/models"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SourceConfig   = "config"
+	SourceFile     = "file"
+	SourceDatabase = "database"
+)
+
+// databaseRefreshInterval avoids querying the MOTD on every login
+const databaseRefreshInterval = time.Minute
+
+// Source provides the current MOTD text
+type Source interface {
+	GetText() (string, error)
+}
+
+// Provider returns the MOTD for a login along with the id of its text: the message of the first
+// matching rule, or the text of the source when no rule matches
+type Provider struct {
+	source Source
+	rules  Rules
+	ids    *IdStore
+}
+
+func NewProvider(source Source, rules Rules, ids *IdStore) *Provider {
+	return &Provider{source: source, rules: rules, ids: ids}
+}
+
+func (p *Provider) Get(request Request) (models.Motd, error) {
+	text, found := p.rules.Select(request, time.Now())
+	if !found {
+		var err error
+		text, err = p.source.GetText()
+		if err != nil {
+			return models.Motd{}, err
+		}
+	}
+
+	if text == "" {
+		return models.Motd{}, nil
+	}
+
+	id, err := p.ids.IdFor(text)
+	return models.Motd{Id: id, Text: text}, err
+}
+
+type ConfigSource struct {
+	text string
+}
+
+func NewConfigSource(text string) *ConfigSource {
+	return &ConfigSource{text: text}
+}
+
+func (s *ConfigSource) GetText() (string, error) {
+	return s.text, nil
+}
+
+// FileSource reads the MOTD from a text file on every call, so edits are picked up right away
+type FileSource struct {
+	filename string
+}
+
+func NewFileSource(filename string) *FileSource {
+	return &FileSource{filename: filename}
+}
+
+func (s *FileSource) GetText() (string, error) {
+	data, err := os.ReadFile(s.filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read MOTD file %s: %w", s.filename, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// DatabaseSource reads the MOTD through the query version, refreshing it at most once a minute
+type DatabaseSource struct {
+	query database.MotdQuery
+	db    *sql.DB
+
+	mu        sync.Mutex
+	text      string
+	fetchedAt time.Time
+}
+
+func NewDatabaseSource(query database.MotdQuery, db *sql.DB) *DatabaseSource {
+	return &DatabaseSource{query: query, db: db}
+}
+
+func (s *DatabaseSource) GetText() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.fetchedAt.IsZero() && time.Since(s.fetchedAt) < databaseRefreshInterval {
+		return s.text, nil
+	}
+
+	text, err := s.query.GetMotd(s.db)
+	if err != nil {
+		return s.text, fmt.Errorf("failed to fetch MOTD: %w", err)
+	}
+
+	s.text = strings.TrimSpace(text)
+	s.fetchedAt = time.Now()
+	return s.text, nil
+}
@@ -0,0 +1,100 @@
+package motd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIdStoreAssignsIdsPerText(t *testing.T) {
+	store, err := NewIdStore("")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	first, _ := store.IdFor("Welcome!")
+	again, _ := store.IdFor("Welcome!")
+	second, _ := store.IdFor("Double XP this weekend")
+
+	if first != firstId {
+		t.Errorf("Expected first id to be %d, got %d", firstId, first)
+	}
+
+	if again != first {
+		t.Errorf("Expected the same text to keep id %d, got %d", first, again)
+	}
+
+	if second != first+1 {
+		t.Errorf("Expected a new text to get id %d, got %d", first+1, second)
+	}
+}
+
+func TestIdStorePersistsIds(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "motd.json")
+
+	store, err := NewIdStore(filename)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	store.IdFor("first")
+	store.IdFor("second")
+
+	reopened, err := NewIdStore(filename)
+	if err != nil {
+		t.Fatalf("Expected no error when reopening, got: %v", err)
+	}
+
+	if id, _ := reopened.IdFor("second"); id != firstId+1 {
+		t.Errorf("Expected persisted id %d, got %d", firstId+1, id)
+	}
+
+	if id, _ := reopened.IdFor("third"); id != firstId+2 {
+		t.Errorf("Expected next id %d after restart, got %d", firstId+2, id)
+	}
+}
+
+func TestProviderWithFileSource(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "motd.txt")
+	if err := os.WriteFile(filename, []byte("Welcome!\n"), 0644); err != nil {
+		t.Fatalf("Failed to write MOTD file: %v", err)
+	}
+
+	store, _ := NewIdStore("")
+	provider := NewProvider(NewFileSource(filename), nil, store)
+
+	motd, err := provider.Get(Request{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if motd.Text != "Welcome!" || motd.Id != firstId {
+		t.Errorf("Unexpected MOTD: %+v", motd)
+	}
+
+	if err := os.WriteFile(filename, []byte("Server save at 10:00"), 0644); err != nil {
+		t.Fatalf("Failed to write MOTD file: %v", err)
+	}
+
+	motd, _ = provider.Get(Request{})
+	if motd.Text != "Server save at 10:00" || motd.Id != firstId+1 {
+		t.Errorf("Expected changed MOTD with a new id, got: %+v", motd)
+	}
+}
+
+func TestProviderEmptyText(t *testing.T) {
+	store, _ := NewIdStore("")
+	provider := NewProvider(NewConfigSource(""), nil, store)
+
+	motd, err := provider.Get(Request{})
+	if err != nil || motd.Text != "" || motd.Id != 0 {
+		t.Errorf("Expected an empty MOTD, got %+v (%v)", motd, err)
+	}
+}
+
+func TestFileSourceMissingFile(t *testing.T) {
+	source := NewFileSource(filepath.Join(t.TempDir(), "missing.txt"))
+
+	if _, err := source.GetText(); err == nil {
+		t.Error("Expected an error for a missing MOTD file, got none")
+	}
+}
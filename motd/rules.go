@@ -0,0 +1,115 @@
+package motd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Request describes the login a MOTD is selected for
+type Request struct {
+	AccountType   uint32
+	ClientVersion uint16
+}
+
+// Rule shows Message to the logins matching all of its conditions; empty conditions match everything
+type Rule struct {
+	Message      string    `yaml:"message"`
+	AccountTypes []uint32  `yaml:"accounttypes"`
+	MinVersion   uint16    `yaml:"minversion"`
+	MaxVersion   uint16    `yaml:"maxversion"`
+	From         time.Time `yaml:"from"`
+	Until        time.Time `yaml:"until"`
+	Weekdays     []string  `yaml:"weekdays"`
+	weekdays     []time.Weekday
+}
+
+type Rules []Rule
+
+type rulesFile struct {
+	Rules Rules `yaml:"rules"`
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// LoadRules reads a YAML file with a list of rules, evaluated in order
+func LoadRules(filename string) (Rules, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read MOTD rules file %s: %w", filename, err)
+	}
+
+	var file rulesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode MOTD rules file %s: %w", filename, err)
+	}
+
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+
+		if strings.TrimSpace(rule.Message) == "" {
+			return nil, fmt.Errorf("MOTD rule %d has no message", i)
+		}
+
+		for _, name := range rule.Weekdays {
+			weekday, found := weekdayNames[strings.ToLower(name)]
+			if !found {
+				return nil, fmt.Errorf("MOTD rule %d has an invalid weekday: %s", i, name)
+			}
+			rule.weekdays = append(rule.weekdays, weekday)
+		}
+	}
+
+	return file.Rules, nil
+}
+
+// Select returns the message of the first rule matching the request at the given time
+func (r Rules) Select(request Request, now time.Time) (string, bool) {
+	for _, rule := range r {
+		if rule.matches(request, now) {
+			return strings.TrimSpace(rule.Message), true
+		}
+	}
+
+	return "", false
+}
+
+func (r *Rule) matches(request Request, now time.Time) bool {
+	if len(r.AccountTypes) > 0 && !slices.Contains(r.AccountTypes, request.AccountType) {
+		return false
+	}
+
+	if r.MinVersion != 0 && request.ClientVersion < r.MinVersion {
+		return false
+	}
+
+	if r.MaxVersion != 0 && request.ClientVersion > r.MaxVersion {
+		return false
+	}
+
+	if !r.From.IsZero() && now.Before(r.From) {
+		return false
+	}
+
+	if !r.Until.IsZero() && !now.Before(r.Until) {
+		return false
+	}
+
+	if len(r.weekdays) > 0 && !slices.Contains(r.weekdays, now.Weekday()) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,97 @@
+package motd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const rulesYaml = `
+rules:
+  - message: Staff meeting at 20:00
+    accounttypes: [3, 4, 5]
+  - message: Please update your client
+    maxversion: 771
+  - message: Double XP this weekend
+    from: 2026-10-23T00:00:00Z
+    until: 2026-10-26T00:00:00Z
+  - message: Happy monday
+    weekdays: [Monday]
+`
+
+func loadTestRules(t *testing.T) Rules {
+	filename := filepath.Join(t.TempDir(), "motd_rules.yaml")
+	if err := os.WriteFile(filename, []byte(rulesYaml), 0644); err != nil {
+		t.Fatalf("Failed to write rules file: %v", err)
+	}
+
+	rules, err := LoadRules(filename)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	return rules
+}
+
+func TestRulesSelect(t *testing.T) {
+	rules := loadTestRules(t)
+
+	saturday := time.Date(2026, 10, 24, 12, 0, 0, 0, time.UTC)
+	tuesday := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	monday := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		request  Request
+		now      time.Time
+		expected string
+		found    bool
+	}{
+		{Request{AccountType: 4, ClientVersion: 772}, saturday, "Staff meeting at 20:00", true},
+		{Request{AccountType: 1, ClientVersion: 760}, saturday, "Please update your client", true},
+		{Request{AccountType: 1, ClientVersion: 772}, saturday, "Double XP this weekend", true},
+		{Request{AccountType: 1, ClientVersion: 772}, monday, "Happy monday", true},
+		{Request{AccountType: 1, ClientVersion: 772}, tuesday, "", false},
+	}
+
+	for _, test := range tests {
+		message, found := rules.Select(test.request, test.now)
+		if message != test.expected || found != test.found {
+			t.Errorf("Select(%+v, %s) = %q, %t; expected %q, %t", test.request, test.now, message, found, test.expected, test.found)
+		}
+	}
+}
+
+func TestRulesUntilIsExclusive(t *testing.T) {
+	rules := loadTestRules(t)
+
+	if message, _ := rules.Select(Request{AccountType: 1, ClientVersion: 772}, time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)); message == "Double XP this weekend" {
+		t.Error("Expected the rule to end at its until time")
+	}
+}
+
+func TestLoadRulesInvalidWeekday(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "motd_rules.yaml")
+	if err := os.WriteFile(filename, []byte("rules:\n  - message: hi\n    weekdays: [caturday]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write rules file: %v", err)
+	}
+
+	if _, err := LoadRules(filename); err == nil {
+		t.Error("Expected an error for an invalid weekday, got none")
+	}
+}
+
+func TestProviderPrefersRules(t *testing.T) {
+	store, _ := NewIdStore("")
+	provider := NewProvider(NewConfigSource("Welcome!"), loadTestRules(t), store)
+
+	staff, _ := provider.Get(Request{AccountType: 5, ClientVersion: 772})
+	if staff.Text != "Staff meeting at 20:00" {
+		t.Errorf("Expected staff MOTD, got %+v", staff)
+	}
+
+	player, _ := provider.Get(Request{AccountType: 1, ClientVersion: 800})
+	if player.Id == staff.Id {
+		t.Errorf("Expected different messages to get different ids, got %d", player.Id)
+	}
+}
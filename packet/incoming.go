@@ -2,6 +2,8 @@ package packet
 
 import (
 	"encoding/binary"
+	"fmt"
+	"go-opentibia-loginserver/crypt"
 )
 
 type Incoming struct {
@@ -68,6 +70,30 @@ func (p *Incoming) GetString() string {
 	return result
 }
 
+// XteaDecrypt decrypts the rest of the packet in place and limits it to the message length written before the
+// message, dropping the padding added by Outgoing.XteaEncrypt
+func (p *Incoming) XteaDecrypt(xteaKey [4]uint32) error {
+	if p.Remaining() == 0 || p.Remaining()%8 != 0 {
+		return fmt.Errorf("encrypted data length %d is not a multiple of 8", p.Remaining())
+	}
+
+	expandedXteaKey := crypt.ExpandXteaKey(xteaKey)
+	crypt.XteaDecrypt(p.PeekBuffer(), &expandedXteaKey)
+
+	messageLength := int(p.GetUint16())
+	if messageLength > p.Remaining() {
+		return fmt.Errorf("decrypted message length %d is larger than the packet", messageLength)
+	}
+
+	p.Resize(p.position + messageLength)
+	return nil
+}
+
+// Remaining returns how many bytes are left to be read
+func (p *Incoming) Remaining() int {
+	return len(p.buffer) - p.position
+}
+
 func (p *Incoming) PeekBuffer() []byte {
 	return p.buffer[p.position:]
 }
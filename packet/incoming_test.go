@@ -253,3 +253,17 @@ func TestIncomingInit(t *testing.T) {
 		t.Errorf("expected buffer size to be 10, but got %d", len(packet.buffer))
 	}
 }
+
+func TestIncomingRemaining(t *testing.T) {
+	packet := NewIncoming(8)
+	packet.Resize(6)
+
+	if packet.Remaining() != 6 {
+		t.Errorf("expected 6 remaining bytes, and got %d", packet.Remaining())
+	}
+
+	packet.GetUint32()
+	if packet.Remaining() != 2 {
+		t.Errorf("expected 2 remaining bytes, and got %d", packet.Remaining())
+	}
+}
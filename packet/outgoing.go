@@ -2,8 +2,8 @@ package packet
 
 import (
 	"encoding/binary"
-	"fmt"
 	"go-opentibia-loginserver/crypt"
+	"log/slog"
 )
 
 const (
@@ -41,7 +41,7 @@ func (p *Outgoing) Get() []byte {
 func (p *Outgoing) AddUint8(data uint8) {
 	offset := HEADER_OFFSET + p.position
 	if (offset + 1) > len(p.buffer) {
-		fmt.Println("Error: Buffer overflow")
+		slog.Error("outgoing packet buffer overflow")
 		return
 	}
 	p.buffer[offset] = data
@@ -51,7 +51,7 @@ func (p *Outgoing) AddUint8(data uint8) {
 func (p *Outgoing) AddBytes(data []byte) {
 	offset := HEADER_OFFSET + p.position
 	if (offset + len(data)) > len(p.buffer) {
-		fmt.Println("Error: Buffer overflow")
+		slog.Error("outgoing packet buffer overflow")
 		return
 	}
 	copy(p.buffer[offset:], data)
@@ -61,7 +61,7 @@ func (p *Outgoing) AddBytes(data []byte) {
 func (p *Outgoing) AddUint16(data uint16) {
 	offset := HEADER_OFFSET + p.position
 	if (offset + 2) > len(p.buffer) {
-		fmt.Println("Error: Buffer overflow")
+		slog.Error("outgoing packet buffer overflow")
 		return
 	}
 	binary.LittleEndian.PutUint16(p.buffer[offset:], data)
@@ -71,7 +71,7 @@ func (p *Outgoing) AddUint16(data uint16) {
 func (p *Outgoing) AddUint32(data uint32) {
 	offset := HEADER_OFFSET + p.position
 	if (offset + 4) > len(p.buffer) {
-		fmt.Println("Error: Buffer overflow")
+		slog.Error("outgoing packet buffer overflow")
 		return
 	}
 	binary.LittleEndian.PutUint32(p.buffer[offset:], data)
@@ -81,14 +81,14 @@ func (p *Outgoing) AddUint32(data uint32) {
 func (p *Outgoing) AddString(data string) {
 	stringLength := len(data)
 	if stringLength > 65535 { // Maximum size of a uint16
-		fmt.Println("Error: String is too long")
+		slog.Error("outgoing packet string is too long", "length", stringLength)
 		return
 	}
 	p.AddUint16(uint16(stringLength))
 
 	offset := HEADER_OFFSET + p.position
 	if offset+stringLength > len(p.buffer) {
-		fmt.Println("Error: Buffer overflow")
+		slog.Error("outgoing packet buffer overflow")
 		return
 	}
 
@@ -118,6 +118,6 @@ func (p *Outgoing) XteaEncrypt(xteaKey [4]uint32) error {
 	p.addPadding()
 
 	expandedXteaKey := crypt.ExpandXteaKey(xteaKey)
-	crypt.XteaEncrypt(p.Get(), expandedXteaKey)
+	crypt.XteaEncrypt(p.Get(), &expandedXteaKey)
 	return nil
 }
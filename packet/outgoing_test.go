@@ -145,3 +145,41 @@ func TestOutgoingXteaEncrypt(t *testing.T) {
 		t.Errorf("Expected size %d after encryption, got %d", expectedSize, packet.Size())
 	}
 }
+
+func TestOutgoingXteaEncryptIncomingXteaDecrypt(t *testing.T) {
+	xteaKey := [4]uint32{0x1, 0x2, 0x3, 0x4}
+
+	outgoing := NewOutgoing(64)
+	outgoing.AddUint8(0x14)
+	outgoing.AddString("Welcome!")
+	outgoing.XteaEncrypt(xteaKey)
+
+	incoming := NewIncoming(64)
+	copy(incoming.PeekBuffer(), outgoing.Get())
+	incoming.Resize(outgoing.Size())
+
+	if err := incoming.XteaDecrypt(xteaKey); err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	if opcode := incoming.GetUint8(); opcode != 0x14 {
+		t.Errorf("Expected opcode 0x14, got 0x%x", opcode)
+	}
+
+	if message := incoming.GetString(); message != "Welcome!" {
+		t.Errorf("Expected message %q, got %q", "Welcome!", message)
+	}
+
+	if incoming.Remaining() != 0 {
+		t.Errorf("Expected padding to be dropped, got %d remaining bytes", incoming.Remaining())
+	}
+}
+
+func TestIncomingXteaDecryptInvalidLength(t *testing.T) {
+	incoming := NewIncoming(64)
+	incoming.Resize(12)
+
+	if err := incoming.XteaDecrypt([4]uint32{0x1, 0x2, 0x3, 0x4}); err == nil {
+		t.Error("Expected error for data that is not a multiple of 8 bytes, but got none")
+	}
+}
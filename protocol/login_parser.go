@@ -1,11 +1,19 @@
 package protocol
 
 import (
+	"encoding/binary"
 	"fmt"
 	"go-opentibia-loginserver/crypt"
 	"go-opentibia-loginserver/packet"
 )
 
+const (
+	// loginHeaderSize is the client os, protocol version and dat, spr and pic signatures sent before the RSA block
+	loginHeaderSize = 16
+	// loginBlockHeaderSize is the zero byte, xtea key, account number and password length starting the RSA block
+	loginBlockHeaderSize = 23
+)
+
 type LoginParser struct {
 	decrypter crypt.Decrypter
 }
@@ -28,17 +36,26 @@ func NewLoginParser(decrypter crypt.Decrypter) *LoginParser {
 func (loginParser *LoginParser) ParseLogin(packet *packet.Incoming) (LoginRequest, error) {
 	var request LoginRequest
 
+	if packet.Remaining() < loginHeaderSize {
+		return request, fmt.Errorf("[parseLogin] - error packet of %d bytes is too short for a login", packet.Remaining())
+	}
+
 	request.ClientOs = packet.GetUint16()
 	request.ProtocolVersion = packet.GetUint16()
 	request.DatSignature = packet.GetUint32()
 	request.SprSignature = packet.GetUint32()
 	request.PicSignature = packet.GetUint32()
 
+	// the RSA block fills the rest of the packet, its length is the size of the key the client was patched with
 	decryptedMsg, err := loginParser.decrypter.DecryptNoPadding(packet.PeekBuffer())
 	if err != nil {
 		return request, fmt.Errorf("[parseLogin] - error while decrypting packet: %w", err)
 	}
 
+	if len(decryptedMsg) < loginBlockHeaderSize {
+		return request, fmt.Errorf("[parseLogin] - error decrypted block of %d bytes is too short", len(decryptedMsg))
+	}
+
 	copy(packet.PeekBuffer(), decryptedMsg)
 
 	if packet.GetUint8() != 0 {
@@ -51,6 +68,10 @@ func (loginParser *LoginParser) ParseLogin(packet *packet.Incoming) (LoginReques
 	request.XteaKey[3] = packet.GetUint32()
 
 	request.AccountNumber = packet.GetUint32()
+
+	if int(binary.LittleEndian.Uint16(packet.PeekBuffer())) > packet.Remaining()-2 {
+		return request, fmt.Errorf("[parseLogin] - error password does not fit in the decrypted packet")
+	}
 	request.Password = packet.GetString()
 
 	return request, nil
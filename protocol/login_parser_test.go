@@ -0,0 +1,81 @@
+package protocol_test
+
+import (
+	"go-opentibia-loginserver/client"
+	"go-opentibia-loginserver/crypt"
+	"go-opentibia-loginserver/packet"
+	"go-opentibia-loginserver/protocol"
+	"testing"
+)
+
+func newTestDecrypter(t *testing.T, bits int) *crypt.RSA {
+	privateKey, err := crypt.GenerateRSAKey(bits)
+	if err != nil {
+		t.Fatalf("Failed to generate test private key: %v", err)
+	}
+
+	return crypt.NewRSADecrypterFromKey("key.pem", privateKey)
+}
+
+// buildLoginPacket returns a login packet positioned after the size and opcode, as a client patched with key sends it
+func buildLoginPacket(t *testing.T, key *crypt.RSA, accountNumber uint32, password string) *packet.Incoming {
+	data, err := client.BuildLoginPacket(key.PublicKey(), protocol.LoginRequest{
+		ClientOs:        2,
+		ProtocolVersion: 772,
+		XteaKey:         [4]uint32{1, 2, 3, 4},
+		AccountNumber:   accountNumber,
+		Password:        password,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build login packet: %v", err)
+	}
+
+	incoming := newIncoming(data)
+	incoming.GetUint16() // message size
+	incoming.GetUint8()  // opcode
+
+	return incoming
+}
+
+func newIncoming(data []byte) *packet.Incoming {
+	incoming := packet.NewIncoming(1024)
+	copy(incoming.PeekBuffer(), data)
+	incoming.Resize(len(data))
+
+	return incoming
+}
+
+func TestParseLoginKeySizes(t *testing.T) {
+	for _, bits := range []int{1024, 2048, 4096} {
+		key := newTestDecrypter(t, bits)
+		parser := protocol.NewLoginParser(key)
+
+		request, err := parser.ParseLogin(buildLoginPacket(t, key, 123456, "secret"))
+		if err != nil {
+			t.Fatalf("Expected no error with a %d-bit key, got: %v", bits, err)
+		}
+
+		if request.ProtocolVersion != 772 || request.AccountNumber != 123456 || request.Password != "secret" || request.XteaKey != [4]uint32{1, 2, 3, 4} {
+			t.Errorf("Unexpected login request with a %d-bit key: %+v", bits, request)
+		}
+	}
+}
+
+func TestParseLoginWrongKeySize(t *testing.T) {
+	clientKey := newTestDecrypter(t, 2048)
+	parser := protocol.NewLoginParser(newTestDecrypter(t, 1024))
+
+	if _, err := parser.ParseLogin(buildLoginPacket(t, clientKey, 123456, "secret")); err == nil {
+		t.Error("Expected error for a block encrypted with a larger key, got none")
+	}
+}
+
+func TestParseLoginShortPacket(t *testing.T) {
+	parser := protocol.NewLoginParser(newTestDecrypter(t, 1024))
+
+	for _, size := range []int{0, 1, 18} {
+		if _, err := parser.ParseLogin(newIncoming(make([]byte, size))); err == nil {
+			t.Errorf("Expected error for a packet of %d bytes, got none", size)
+		}
+	}
+}
@@ -11,6 +11,9 @@ import (
 
 const PACKET_SIZE = 1024
 
+// MaxCharacters is the most characters a character list can count, the count is a single byte
+const MaxCharacters = 255
+
 func SendClientError(conn net.Conn, xteaKey [4]uint32, errorData string) {
 	packet := packet.NewOutgoing(PACKET_SIZE)
 	packet.AddUint8(0x0A)
@@ -19,28 +22,27 @@ func SendClientError(conn net.Conn, xteaKey [4]uint32, errorData string) {
 	SendData(conn, xteaKey, packet)
 }
 
-func SendClientMotdAndCharacterList(conn net.Conn, xteaKey [4]uint32, motd string, accountInfo *models.AccountInfo, cfg *config.Config) {
+// SendClientMotdAndCharacterList sends the characters on world, advertised at worldIp and worldPort. Characters
+// past the ones FitCharacters counts are left out, as the client can not parse a list cut by the packet size.
+func SendClientMotdAndCharacterList(conn net.Conn, xteaKey [4]uint32, motd models.Motd, accountInfo *models.AccountInfo, world *config.World, worldIp uint32, worldPort uint16) error {
 	packet := packet.NewOutgoing(PACKET_SIZE)
 
 	// motd
-	if motd != "" {
+	if motd.Text != "" {
 		packet.AddUint8(0x14)
-		packet.AddString(fmt.Sprintf("%d\n%s", 1, motd))
+		packet.AddString(motdMessage(motd))
 	}
 
 	// character list
 	packet.AddUint8(0x64)
-	characterListLength := len(accountInfo.Characters)
+	characterListLength := FitCharacters(motd, accountInfo.Characters, world.Name)
 	packet.AddUint8(uint8(characterListLength))
 
-	//there is no support for multiworld yet, so get the default world
-	world := config.GetDefaultWorld(cfg)
-
 	for i := 0; i < characterListLength; i++ {
 		packet.AddString(accountInfo.Characters[i])
 		packet.AddString(world.Name)
-		packet.AddUint32(world.HostIP)
-		packet.AddUint16(world.Port)
+		packet.AddUint32(worldIp)
+		packet.AddUint16(worldPort)
 	}
 
 	premiumDays := utils.CalculateRemainingDays(accountInfo.PremiumEndsAt)
@@ -50,7 +52,30 @@ func SendClientMotdAndCharacterList(conn net.Conn, xteaKey [4]uint32, motd strin
 		packet.AddUint16(uint16(premiumDays))
 	}
 
-	SendData(conn, xteaKey, packet)
+	return SendData(conn, xteaKey, packet)
+}
+
+// FitCharacters returns how many of the characters, in order, fit in a login packet with motd on world worldName
+func FitCharacters(motd models.Motd, characters []string, worldName string) int {
+	// opcode and count, then the premium days after the list
+	size := 2 + 2
+	if motd.Text != "" {
+		size += 1 + 2 + len(motdMessage(motd))
+	}
+
+	// PACKET_SIZE is the payload, the room NewOutgoing adds past it holds the length header and XTEA padding
+	for i, character := range characters {
+		size += 2 + len(character) + 2 + len(worldName) + 4 + 2
+		if size > PACKET_SIZE || i == MaxCharacters {
+			return i
+		}
+	}
+
+	return len(characters)
+}
+
+func motdMessage(motd models.Motd) string {
+	return fmt.Sprintf("%d\n%s", motd.Id, motd.Text)
 }
 
 func SendData(conn net.Conn, xteaKey [4]uint32, packet *packet.Outgoing) error {
@@ -0,0 +1,94 @@
+package protocol
+
+import (
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/models"
+	"go-opentibia-loginserver/packet"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestFitCharacters(t *testing.T) {
+	many := make([]string, 300)
+	for i := range many {
+		many[i] = "A"
+	}
+
+	// 4 bytes of opcode, count and premium days, then 2+1+2+4+2 bytes for every character
+	if count := FitCharacters(models.Motd{}, many, ""); count != (PACKET_SIZE-4)/11 || count > MaxCharacters {
+		t.Errorf("Expected %d characters to fit, got %d", (PACKET_SIZE-4)/11, count)
+	}
+
+	long := make([]string, 100)
+	for i := range long {
+		long[i] = strings.Repeat("B", 40)
+	}
+
+	// 2+40+2+4+4+2 bytes for every character
+	if count := FitCharacters(models.Motd{}, long, "Test"); count != (PACKET_SIZE-4)/54 {
+		t.Errorf("Expected %d characters to fit, got %d", (PACKET_SIZE-4)/54, count)
+	}
+
+	if count := FitCharacters(models.Motd{Id: 1, Text: strings.Repeat("M", 500)}, long, "Test"); count >= (PACKET_SIZE-4)/54 {
+		t.Errorf("Expected the MOTD to leave room for fewer characters, got %d", count)
+	}
+}
+
+func TestSendClientMotdAndCharacterListCapsCharacters(t *testing.T) {
+	xteaKey := [4]uint32{1, 2, 3, 4}
+	motd := models.Motd{Id: 1, Text: "Welcome!"}
+	world := &config.World{Name: "Test"}
+
+	characters := make([]string, 100)
+	for i := range characters {
+		characters[i] = strings.Repeat("C", 30)
+	}
+	expected := FitCharacters(motd, characters, world.Name)
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	go func() {
+		defer serverConn.Close()
+		SendClientMotdAndCharacterList(serverConn, xteaKey, motd, &models.AccountInfo{Characters: characters}, world, 0x0100007F, 7172)
+	}()
+
+	data, err := io.ReadAll(clientConn)
+	if err != nil {
+		t.Fatalf("Failed to read packet: %v", err)
+	}
+
+	incoming := packet.NewIncoming(len(data))
+	copy(incoming.PeekBuffer(), data)
+	if size := int(incoming.GetUint16()); size != len(data)-2 {
+		t.Fatalf("Expected size header %d, got %d", len(data)-2, size)
+	}
+
+	if err := incoming.XteaDecrypt(xteaKey); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	incoming.GetUint8() // motd opcode
+	incoming.GetString()
+	incoming.GetUint8() // character list opcode
+
+	count := int(incoming.GetUint8())
+	if count != expected || count == len(characters) {
+		t.Fatalf("Expected the list to be capped to %d characters, got %d", expected, count)
+	}
+
+	for i := 0; i < count; i++ {
+		if name := incoming.GetString(); name != characters[i] {
+			t.Fatalf("Expected character %d to be %q, got %q", i, characters[i], name)
+		}
+		incoming.GetString() // world
+		incoming.GetUint32()
+		incoming.GetUint16()
+	}
+
+	if incoming.Remaining() != 2 {
+		t.Errorf("Expected only the premium days after the list, got %d bytes", incoming.Remaining())
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"go-opentibia-loginserver/config"
+	"go-opentibia-loginserver/logging"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// handleShutdownSignals closes listener when the process receives SIGINT or SIGTERM, which ends the accept loop
+func handleShutdownSignals(listener net.Listener) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		received := <-signals
+		signal.Stop(signals)
+		slog.Info("shutting down", "reason", received.String()+" received")
+		listener.Close()
+	}()
+}
+
+// waitForHandlers waits up to timeout for the connections being handled, so their login attempts reach the audit log
+func (s *LoginServer) waitForHandlers(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Warn("connections still open at shutdown", "timeout", timeout)
+	}
+}
+
+// handleReloadSignals reloads the config whenever the process receives SIGHUP
+func (s *LoginServer) handleReloadSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			s.reloadConfigAndLog("SIGHUP received")
+		}
+	}()
+}
+
+// handleMaintenanceSignals turns maintenance on when the process receives SIGUSR1 and off on SIGUSR2
+func (s *LoginServer) handleMaintenanceSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	go func() {
+		for received := range signals {
+			enabled := received == syscall.SIGUSR1
+			s.maintenance.Set(enabled, "")
+			slog.Info("maintenance mode changed", "enabled", enabled, "reason", received.String()+" received")
+		}
+	}()
+}
+
+func (s *LoginServer) watchConfigFile() {
+	err := config.WatchConfig(func() {
+		s.reloadConfigAndLog("config file changed")
+	})
+	if err != nil {
+		slog.Error("could not watch the config file", logging.KeyError, err)
+	}
+}
+
+func (s *LoginServer) reloadConfigAndLog(reason string) {
+	slog.Info("reloading config", "reason", reason)
+
+	if err := s.ReloadConfig(); err != nil {
+		slog.Error("could not reload config", logging.KeyError, err)
+	}
+}
@@ -37,3 +37,7 @@ func IpToUint32(ipStr string) (uint32, error) {
 
 	return uint32(ip[3])<<24 | uint32(ip[2])<<16 | uint32(ip[1])<<8 | uint32(ip[0]), nil
 }
+
+func Uint32ToIp(ip uint32) net.IP {
+	return net.IPv4(byte(ip), byte(ip>>8), byte(ip>>16), byte(ip>>24))
+}
@@ -92,3 +92,21 @@ func TestIpToUint32(t *testing.T) {
 		}
 	}
 }
+
+func TestUint32ToIp(t *testing.T) {
+	tests := []struct {
+		ip         uint32
+		expectedIP string
+	}{
+		{16885952, "192.168.1.1"},
+		{16777226, "10.0.0.1"},
+		{0, "0.0.0.0"},
+	}
+
+	for _, test := range tests {
+		ip := Uint32ToIp(test.ip)
+		if ip.String() != test.expectedIP {
+			t.Errorf("expected %s, got %s for IP %d", test.expectedIP, ip, test.ip)
+		}
+	}
+}